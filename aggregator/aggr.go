@@ -1,12 +1,44 @@
 package aggregator
 
+import "context"
+
+// Service statuses reported in health entries
+const (
+	StatusUp       = "UP"
+	StatusDown     = "DOWN"
+	StatusUnknown  = "UNKNOWN"
+	StatusTimeout  = "TIMEOUT"
+	StatusFlapping = "FLAPPING"
+	// StatusMaintenance replaces failures of services while maintenance mode is active
+	StatusMaintenance = "MAINTENANCE"
+	// StatusDegraded is an overall status when some of the services aren't UP
+	StatusDegraded = "DEGRADED"
+)
+
+// KeyCritical marks health entries of services bringing overall status down once they fail
+const KeyCritical = "critical"
+
+// IsCritical reports whether the health entry belongs to a critical service
+func IsCritical(entry interface{}) bool {
+	e, _ := entry.(map[string]interface{})
+	critical, _ := e[KeyCritical].(bool)
+
+	return critical
+}
+
 type (
 	// Aggregator collects information from all available services
 	Aggregator interface {
 		// AggregateInfo collects information from info endpoints
-		AggregateInfo() map[string]interface{}
+		AggregateInfo(ctx context.Context) map[string]interface{}
 
 		// AggregateHealth aggregates information from health endpoints
-		AggregateHealth() map[string]interface{}
+		AggregateHealth(ctx context.Context) map[string]interface{}
+
+		// Services returns names of discovered services
+		Services(ctx context.Context) ([]string, error)
+
+		// Discover resolves nodes and describes the result along with the ignored objects
+		Discover(ctx context.Context) *Discovery
 	}
 )
@@ -0,0 +1,129 @@
+// Package aggregatortest provides aggregator stubs for tests of decorators and handlers
+package aggregatortest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+// Stub reports the configured info and health, entries not matching filter of the context are omitted
+type Stub struct {
+	// Info is reported by AggregateInfo
+	Info map[string]interface{}
+	// Health is reported by AggregateHealth once Script is exhausted, use SetHealth while the stub is in use
+	Health map[string]interface{}
+	// Script holds health reported by consecutive calls, one entry per call
+	Script []map[string]interface{}
+	// Delay is a time every call takes
+	Delay time.Duration
+
+	mu          sync.Mutex
+	infoCalls   int
+	healthCalls int
+}
+
+// Statuses creates health entries of services with the statuses
+func Statuses(statuses map[string]string) map[string]interface{} {
+	health := make(map[string]interface{}, len(statuses))
+	for name, status := range statuses {
+		health[name] = map[string]interface{}{"status": status}
+	}
+
+	return health
+}
+
+// SetHealth replaces health reported by the stub
+func (s *Stub) SetHealth(health map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Health = health
+}
+
+// InfoCalls returns number of AggregateInfo calls
+func (s *Stub) InfoCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.infoCalls
+}
+
+// HealthCalls returns number of AggregateHealth calls
+func (s *Stub) HealthCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.healthCalls
+}
+
+// AggregateInfo reports the configured info
+func (s *Stub) AggregateInfo(ctx context.Context) map[string]interface{} {
+	time.Sleep(s.Delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.infoCalls++
+
+	return filter(ctx, s.Info)
+}
+
+// AggregateHealth reports the next scripted health, or the configured one once the script is exhausted
+func (s *Stub) AggregateHealth(ctx context.Context) map[string]interface{} {
+	time.Sleep(s.Delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthCalls++
+
+	health := s.Health
+	if len(s.Script) > 0 {
+		health, s.Script = s.Script[0], s.Script[1:]
+	}
+
+	return filter(ctx, health)
+}
+
+// Services returns names of services reporting either info or health
+func (s *Stub) Services(context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	known := make(map[string]struct{}, len(s.Info)+len(s.Health))
+	for _, entries := range []map[string]interface{}{s.Info, s.Health} {
+		for name := range entries {
+			known[name] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Discover describes the services as discovered nodes
+func (s *Stub) Discover(ctx context.Context) *aggregator.Discovery {
+	d := aggregator.NewDiscovery()
+	names, _ := s.Services(ctx)
+	for _, name := range names {
+		d.Add(aggregator.DiscoveredNode{Service: name})
+	}
+
+	return d
+}
+
+// filter copies entries matching filter of the context
+func filter(ctx context.Context, entries map[string]interface{}) map[string]interface{} {
+	f := aggregator.FilterFrom(ctx)
+	res := make(map[string]interface{}, len(entries))
+	for name, entry := range entries {
+		if f.Match(name) {
+			res[name] = entry
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,76 @@
+package aggregator
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	infoKey   = "info"
+	healthKey = "health"
+)
+
+// coalescing shares a single in-flight aggregation between concurrent callers
+type coalescing struct {
+	delegate Aggregator
+	group    singleflight.Group
+}
+
+// NewCoalescing wraps the aggregator so that concurrent callers share one in-flight aggregation
+func NewCoalescing(delegate Aggregator) Aggregator {
+	return &coalescing{delegate: delegate}
+}
+
+// AggregateInfo collects information from info endpoints
+func (c *coalescing) AggregateInfo(ctx context.Context) map[string]interface{} {
+	return c.do(ctx, infoKey, c.delegate.AggregateInfo)
+}
+
+// AggregateHealth aggregates information from health endpoints
+func (c *coalescing) AggregateHealth(ctx context.Context) map[string]interface{} {
+	return c.do(ctx, healthKey, c.delegate.AggregateHealth)
+}
+
+// Services returns names of discovered services
+func (c *coalescing) Services(ctx context.Context) ([]string, error) {
+	return c.delegate.Services(ctx)
+}
+
+// Discover resolves nodes and describes the result along with the ignored objects
+func (c *coalescing) Discover(ctx context.Context) *Discovery {
+	return c.delegate.Discover(ctx)
+}
+
+func (c *coalescing) do(
+	ctx context.Context,
+	key string,
+	f func(ctx context.Context) map[string]interface{},
+) map[string]interface{} {
+	// the shared aggregation must not be canceled once the caller started it goes away
+	shared := context.WithoutCancel(ctx)
+	// only aggregations of the same services may be shared
+	if filter := FilterFrom(ctx); filter != nil {
+		key += "?" + filter.key()
+	}
+	// check rounds advance smoothed statuses, so they must not join aggregations of requests
+	if IsCheckRound(ctx) {
+		key += "#round"
+	}
+	v, _, _ := c.group.Do(key, func() (interface{}, error) {
+		return f(shared), nil
+	})
+
+	res, ok := v.(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+
+	// every caller gets its own copy of the result so that it may be modified safely
+	cp := make(map[string]interface{}, len(res))
+	for k, v := range res {
+		cp[k] = v
+	}
+
+	return cp
+}
@@ -0,0 +1,35 @@
+package aggregator_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/aggregator/aggregatortest"
+)
+
+func TestNewCoalescing(t *testing.T) {
+	delegate := &aggregatortest.Stub{
+		Health: aggregatortest.Statuses(map[string]string{"api": aggregator.StatusUp}),
+		Delay:  50 * time.Millisecond,
+	}
+	a := aggregator.NewCoalescing(delegate)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := a.AggregateHealth(context.Background()); len(got) != 1 {
+				t.Errorf("AggregateHealth() = %v", got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls := delegate.HealthCalls(); calls != 1 {
+		t.Errorf("delegate called %d times, want 1", calls)
+	}
+}
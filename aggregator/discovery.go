@@ -0,0 +1,64 @@
+package aggregator
+
+import (
+	"sort"
+	"time"
+)
+
+// Discovery describes nodes resolved by a discovery attempt
+type Discovery struct {
+	Time    time.Time        `json:"time"`
+	Nodes   []DiscoveredNode `json:"nodes"`
+	Skipped []SkippedNode    `json:"skipped,omitempty"`
+	// Error is a raw error of the discovery attempt, if any
+	Error string `json:"error,omitempty"`
+}
+
+// DiscoveredNode describes a node included into composite responses
+type DiscoveredNode struct {
+	Service string `json:"service"`
+	// Source is an object the node was discovered from, e.g. Kubernetes Service or Traefik service
+	Source string `json:"source"`
+	// Target is a resolved URL or SRV record of the node
+	Target         string `json:"target,omitempty"`
+	ProbeType      string `json:"probeType,omitempty"`
+	InfoEndpoint   string `json:"infoEndpoint,omitempty"`
+	HealthEndpoint string `json:"healthEndpoint,omitempty"`
+	// Critical nodes bring overall status down once they fail
+	Critical  bool     `json:"critical,omitempty"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// SkippedNode describes an object ignored by discovery
+type SkippedNode struct {
+	Source string `json:"source"`
+	Reason string `json:"reason"`
+}
+
+// NewDiscovery creates empty discovery report
+func NewDiscovery() *Discovery {
+	return &Discovery{Time: time.Now(), Nodes: []DiscoveredNode{}}
+}
+
+// Skip records an object ignored by discovery, it's a no-op for nil discovery
+func (d *Discovery) Skip(source, reason string) {
+	if d == nil {
+		return
+	}
+	d.Skipped = append(d.Skipped, SkippedNode{Source: source, Reason: reason})
+}
+
+// Add records discovered nodes keeping them sorted by service name
+func (d *Discovery) Add(nodes ...DiscoveredNode) {
+	d.Nodes = append(d.Nodes, nodes...)
+	sort.SliceStable(d.Nodes, func(i, j int) bool {
+		return d.Nodes[i].Service < d.Nodes[j].Service
+	})
+}
+
+// Fail records error of the discovery attempt
+func (d *Discovery) Fail(err error) {
+	if err != nil {
+		d.Error = err.Error()
+	}
+}
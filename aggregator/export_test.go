@@ -0,0 +1,8 @@
+package aggregator
+
+import "time"
+
+// SetClock replaces clock of the hysteresis aggregator
+func SetClock(a Aggregator, now func() time.Time) {
+	a.(*hysteresis).now = now
+}
@@ -0,0 +1,111 @@
+package aggregator
+
+import (
+	"context"
+	"sort"
+)
+
+// nodeResult is a result of a single node probe
+type nodeResult struct {
+	node string
+	res  interface{}
+	err  error
+}
+
+// Fanout probes nodes using a pool of at most limit workers and collects results keyed by node name.
+// Zero limit means a worker per node. Results of failed probes are omitted. Once the context is done,
+// the nodes which are still being probed or waiting for a worker are returned as unfinished.
+// Nodes not matching filter of the context aren't probed at all
+func Fanout[T any](
+	ctx context.Context,
+	nodes map[string]T,
+	limit int,
+	f func(ctx context.Context, node T) (interface{}, error),
+) (aggregated map[string]interface{}, unfinished []string) {
+	nodes = filterNodes(ctx, nodes)
+	results := startWorkers(ctx, nodes, limit, f)
+
+	pending := make(map[string]struct{}, len(nodes))
+	for node := range nodes {
+		pending[node] = struct{}{}
+	}
+	aggregated = make(map[string]interface{}, len(nodes))
+	for len(pending) > 0 {
+		select {
+		case r := <-results:
+			delete(pending, r.node)
+			if nil == r.err {
+				aggregated[r.node] = r.res
+			}
+		case <-ctx.Done():
+			for node := range pending {
+				unfinished = append(unfinished, node)
+			}
+			sort.Strings(unfinished)
+
+			return aggregated, unfinished
+		}
+	}
+
+	return aggregated, nil
+}
+
+// MarkTimeouts reports unfinished nodes with TIMEOUT status in the health entries
+func MarkTimeouts(health map[string]interface{}, unfinished []string) map[string]interface{} {
+	for _, node := range unfinished {
+		health[node] = map[string]interface{}{"status": StatusTimeout}
+	}
+
+	return health
+}
+
+// filterNodes returns nodes matching filter of the context
+func filterNodes[T any](ctx context.Context, nodes map[string]T) map[string]T {
+	filter := FilterFrom(ctx)
+	if filter == nil {
+		return nodes
+	}
+
+	matched := make(map[string]T, len(nodes))
+	for name, node := range nodes {
+		if filter.Match(name) {
+			matched[name] = node
+		}
+	}
+
+	return matched
+}
+
+// startWorkers probes the nodes by at most limit workers until the context is done
+func startWorkers[T any](
+	ctx context.Context,
+	nodes map[string]T,
+	limit int,
+	f func(ctx context.Context, node T) (interface{}, error),
+) <-chan nodeResult {
+	if limit <= 0 || limit > len(nodes) {
+		limit = len(nodes)
+	}
+
+	jobs := make(chan string, len(nodes))
+	for node := range nodes {
+		jobs <- node
+	}
+	close(jobs)
+
+	// buffered so that workers finishing after the deadline never block
+	results := make(chan nodeResult, len(nodes))
+	for i := 0; i < limit; i++ {
+		go func() {
+			for node := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				res, err := f(ctx, nodes[node])
+				results <- nodeResult{node: node, res: res, err: err}
+			}
+		}()
+	}
+
+	return results
+}
@@ -0,0 +1,82 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFanout(t *testing.T) {
+	nodes := map[string]time.Duration{
+		"api":      0,
+		"uat":      0,
+		"analyzer": time.Second,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	got, unfinished := Fanout(ctx, nodes, 0, func(ctx context.Context, delay time.Duration) (interface{}, error) {
+		if delay == 0 {
+			return map[string]interface{}{"status": StatusUp}, nil
+		}
+		select {
+		case <-time.After(delay):
+			return nil, errors.New("not expected")
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+
+	if len(got) != 2 || !reflect.DeepEqual(unfinished, []string{"analyzer"}) {
+		t.Fatalf("Fanout() = %v, unfinished %v, want api and uat finished", got, unfinished)
+	}
+	health := MarkTimeouts(got, unfinished)
+	for node, want := range map[string]string{"api": StatusUp, "uat": StatusUp, "analyzer": StatusTimeout} {
+		if status := health[node].(map[string]interface{})["status"]; status != want {
+			t.Errorf("MarkTimeouts() %s status = %v, want %v", node, status, want)
+		}
+	}
+}
+
+func TestFanout_OmitsFailed(t *testing.T) {
+	got, unfinished := Fanout(context.Background(), map[string]bool{"api": true, "uat": false}, 1,
+		func(_ context.Context, ok bool) (interface{}, error) {
+			if !ok {
+				return nil, errors.New("unavailable")
+			}
+
+			return "info", nil
+		})
+	if len(got) != 1 || got["api"] != "info" || unfinished != nil {
+		t.Errorf("Fanout() = %v, unfinished %v", got, unfinished)
+	}
+}
+
+func TestFanout_Limit(t *testing.T) {
+	nodes := map[string]int{"api": 1, "uat": 2, "ui": 3, "jobs": 4, "analyzer": 5}
+	var running, maxRunning int32
+
+	got, _ := Fanout(context.Background(), nodes, 2, func(_ context.Context, n int) (interface{}, error) {
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			prev := atomic.LoadInt32(&maxRunning)
+			if cur <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, cur) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		return n, nil
+	})
+
+	if len(got) != len(nodes) {
+		t.Errorf("Fanout() returned %d entries, want %d", len(got), len(nodes))
+	}
+	if maxRunning > 2 {
+		t.Errorf("Fanout() ran %d probes simultaneously, want at most 2", maxRunning)
+	}
+}
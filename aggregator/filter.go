@@ -0,0 +1,101 @@
+package aggregator
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type filterKey struct{}
+
+// Filter selects services included into composite responses
+type Filter struct {
+	// Include lists selected services, empty means all of them
+	Include []string
+	// Exclude lists services omitted from the response
+	Exclude []string
+}
+
+// ParseFilter parses comma-separated lists of included and excluded services
+func ParseFilter(include, exclude string) *Filter {
+	f := &Filter{Include: splitNames(include), Exclude: splitNames(exclude)}
+	if len(f.Include) == 0 && len(f.Exclude) == 0 {
+		return nil
+	}
+
+	return f
+}
+
+// Match reports whether the service passes the filter, nil filter matches everything
+func (f *Filter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Include) > 0 && !slices.Contains(f.Include, name) {
+		return false
+	}
+
+	return !slices.Contains(f.Exclude, name)
+}
+
+// Unknown returns included services missing among the known ones
+func (f *Filter) Unknown(known []string) []string {
+	if f == nil {
+		return nil
+	}
+
+	var unknown []string
+	for _, name := range f.Include {
+		if !slices.Contains(known, name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	return unknown
+}
+
+// key identifies the filter, equal filters have equal keys
+func (f *Filter) key() string {
+	if f == nil {
+		return ""
+	}
+
+	return strings.Join(f.Include, ",") + "!" + strings.Join(f.Exclude, ",")
+}
+
+// WithFilter returns context limiting aggregations to the services matching the filter
+func WithFilter(ctx context.Context, f *Filter) context.Context {
+	return context.WithValue(ctx, filterKey{}, f)
+}
+
+// FilterFrom returns filter of the context, nil if there is none
+func FilterFrom(ctx context.Context) *Filter {
+	f, _ := ctx.Value(filterKey{}).(*Filter)
+
+	return f
+}
+
+// splitNames splits comma-separated list into sorted unique names
+func splitNames(s string) []string {
+	var names []string
+	for _, n := range strings.Split(s, ",") {
+		if n = strings.TrimSpace(n); n != "" && !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Names returns sorted names of the nodes
+func Names[T any](nodes map[string]T) []string {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
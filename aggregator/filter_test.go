@@ -0,0 +1,48 @@
+package aggregator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	if f := ParseFilter("", " "); f != nil {
+		t.Errorf("ParseFilter() = %+v, want nil", f)
+	}
+
+	f := ParseFilter("uat, api,api", "jobs")
+	if !reflect.DeepEqual(f.Include, []string{"api", "uat"}) || !reflect.DeepEqual(f.Exclude, []string{"jobs"}) {
+		t.Errorf("ParseFilter() = %+v", f)
+	}
+	for name, want := range map[string]bool{"api": true, "uat": true, "ui": false, "jobs": false} {
+		if got := f.Match(name); got != want {
+			t.Errorf("Match(%s) = %v, want %v", name, got, want)
+		}
+	}
+	if got := f.Unknown([]string{"api", "ui"}); !reflect.DeepEqual(got, []string{"uat"}) {
+		t.Errorf("Unknown() = %v, want [uat]", got)
+	}
+}
+
+func TestFanout_Filter(t *testing.T) {
+	nodes := map[string]string{"api": "api", "uat": "uat", "ui": "ui"}
+	ctx := WithFilter(context.Background(), ParseFilter("", "ui"))
+
+	probed := make(chan string, len(nodes))
+	res, _ := Fanout(ctx, nodes, 0, func(_ context.Context, node string) (interface{}, error) {
+		probed <- node
+
+		return node, nil
+	})
+	close(probed)
+
+	if !reflect.DeepEqual(Names(res), []string{"api", "uat"}) {
+		t.Errorf("Fanout() = %v, want api and uat only", res)
+	}
+	for node := range probed {
+		if node == "ui" {
+			t.Error("Fanout() probed excluded node")
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package aggregator
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HysteresisConfig holds settings smoothing status changes of services
+type HysteresisConfig struct {
+	// FailureThreshold is a number of consecutive failed checks before a service is reported DOWN
+	FailureThreshold int `env:"HEALTH_FAILURE_THRESHOLD" envDefault:"1"`
+	// SuccessThreshold is a number of consecutive successful checks before a service is reported UP
+	SuccessThreshold int `env:"HEALTH_SUCCESS_THRESHOLD" envDefault:"1"`
+	// FlapThreshold is a number of status changes within the window marking a service FLAPPING, zero disables it
+	FlapThreshold int           `env:"FLAP_THRESHOLD" envDefault:"0"`
+	FlapWindow    time.Duration `env:"FLAP_WINDOW"    envDefault:"10m"`
+}
+
+// hysteresis reports status change of a service only once it is confirmed by consecutive check rounds
+type hysteresis struct {
+	delegate Aggregator
+	cfg      *HysteresisConfig
+	now      func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*serviceState
+}
+
+// serviceState tracks confirmed status of a service along with checks contradicting it
+type serviceState struct {
+	status string
+	// pending is a number of consecutive checks contradicting the status,
+	// i.e. failures of an UP service or successes of a failed one
+	pending int
+	changes []time.Time
+
+	reported string
+	since    time.Time
+}
+
+// Enabled reports whether statuses are smoothed or flapping services are detected
+func (cfg *HysteresisConfig) Enabled() bool {
+	return cfg.FailureThreshold > 1 || cfg.SuccessThreshold > 1 || cfg.FlapThreshold > 0
+}
+
+// NewHysteresis wraps the aggregator so that health statuses are smoothed and flapping services are detected.
+// Statuses advance in check rounds only, see WithCheckRound. Every health entry gets a since timestamp of its current status
+func NewHysteresis(delegate Aggregator, cfg *HysteresisConfig) Aggregator {
+	return &hysteresis{delegate: delegate, cfg: cfg, now: time.Now, states: map[string]*serviceState{}}
+}
+
+// AggregateInfo collects information from info endpoints
+func (h *hysteresis) AggregateInfo(ctx context.Context) map[string]interface{} {
+	return h.delegate.AggregateInfo(ctx)
+}
+
+// Services returns names of discovered services
+func (h *hysteresis) Services(ctx context.Context) ([]string, error) {
+	return h.delegate.Services(ctx)
+}
+
+// Discover resolves nodes and describes the result along with the ignored objects
+func (h *hysteresis) Discover(ctx context.Context) *Discovery {
+	return h.delegate.Discover(ctx)
+}
+
+// AggregateHealth aggregates information from health endpoints smoothing status changes
+func (h *hysteresis) AggregateHealth(ctx context.Context) map[string]interface{} {
+	health := h.delegate.AggregateHealth(ctx)
+	round := IsCheckRound(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	if round && FilterFrom(ctx) == nil {
+		h.prune(health)
+	}
+	for name, entry := range health {
+		e, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		observed, _ := e["status"].(string)
+		if observed == "" {
+			observed = StatusUnknown
+		}
+		st := h.state(name, observed, now, round)
+
+		res := make(map[string]interface{}, len(e)+2)
+		for k, v := range e {
+			res[k] = v
+		}
+		res["status"] = st.reported
+		res["since"] = st.since
+		if st.reported != observed {
+			res["observedStatus"] = observed
+		}
+		health[name] = res
+	}
+
+	return health
+}
+
+// prune forgets states of services which are gone
+func (h *hysteresis) prune(health map[string]interface{}) {
+	for name := range h.states {
+		if _, ok := health[name]; !ok {
+			delete(h.states, name)
+		}
+	}
+}
+
+// state returns state of the service, the observed status is registered in check rounds only
+func (h *hysteresis) state(name, observed string, now time.Time, round bool) *serviceState {
+	st, ok := h.states[name]
+	if !ok {
+		st = &serviceState{status: observed, reported: observed, since: now}
+		if round {
+			h.states[name] = st
+		}
+
+		return st
+	}
+	if round {
+		h.observe(st, observed, now)
+	}
+
+	return st
+}
+
+// observe registers the observed status of the service and updates the reported one
+func (h *hysteresis) observe(st *serviceState, observed string, now time.Time) {
+	threshold := h.cfg.FailureThreshold
+	if observed == StatusUp {
+		threshold = h.cfg.SuccessThreshold
+	}
+	st.confirm(observed, threshold, now)
+	st.trimChanges(now, h.cfg.FlapWindow)
+
+	reported := st.status
+	if h.cfg.FlapThreshold > 0 && len(st.changes) >= h.cfg.FlapThreshold {
+		reported = StatusFlapping
+	}
+	if reported != st.reported {
+		st.reported, st.since = reported, now
+	}
+}
+
+// confirm switches the status once the observed one contradicts it threshold times in a row.
+// Any non-UP status counts as a failure, so failures of different kinds don't reset the count
+func (st *serviceState) confirm(observed string, threshold int, now time.Time) {
+	if (observed == StatusUp) == (st.status == StatusUp) {
+		// a failed service keeps the latest kind of failure
+		st.status, st.pending = observed, 0
+
+		return
+	}
+
+	st.pending++
+	if st.pending >= threshold {
+		st.status, st.pending = observed, 0
+		st.changes = append(st.changes, now)
+	}
+}
+
+// trimChanges keeps only status changes within the window
+func (st *serviceState) trimChanges(now time.Time, window time.Duration) {
+	i := 0
+	for i < len(st.changes) && now.Sub(st.changes[i]) > window {
+		i++
+	}
+	st.changes = st.changes[i:]
+}
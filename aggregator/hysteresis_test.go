@@ -0,0 +1,118 @@
+package aggregator_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/aggregator/aggregatortest"
+)
+
+func TestHysteresis(t *testing.T) {
+	const (
+		up       = aggregator.StatusUp
+		down     = aggregator.StatusDown
+		timeout  = aggregator.StatusTimeout
+		flapping = aggregator.StatusFlapping
+	)
+	tests := []struct {
+		name     string
+		cfg      aggregator.HysteresisConfig
+		observed []string
+		want     []string
+	}{
+		{
+			name:     "disabled",
+			cfg:      aggregator.HysteresisConfig{FailureThreshold: 1, SuccessThreshold: 1},
+			observed: []string{up, down, up},
+			want:     []string{up, down, up},
+		},
+		{
+			name:     "consecutive failures",
+			cfg:      aggregator.HysteresisConfig{FailureThreshold: 2, SuccessThreshold: 2},
+			observed: []string{up, timeout, up, down, down, up, up},
+			want:     []string{up, up, up, up, down, down, up},
+		},
+		{
+			name:     "failures of different kinds",
+			cfg:      aggregator.HysteresisConfig{FailureThreshold: 2, SuccessThreshold: 2},
+			observed: []string{up, down, timeout, down, timeout, up, down},
+			want:     []string{up, up, timeout, down, timeout, timeout, down},
+		},
+		{
+			name:     "flapping",
+			cfg:      aggregator.HysteresisConfig{FailureThreshold: 1, SuccessThreshold: 1, FlapThreshold: 3, FlapWindow: time.Hour},
+			observed: []string{up, down, up, down, down},
+			want:     []string{up, down, up, flapping, flapping},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delegate := &aggregatortest.Stub{}
+			for _, status := range tt.observed {
+				delegate.Script = append(delegate.Script, aggregatortest.Statuses(map[string]string{"api": status}))
+			}
+			h := aggregator.NewHysteresis(delegate, &tt.cfg)
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			aggregator.SetClock(h, func() time.Time { return now })
+
+			var since time.Time
+			for i, want := range tt.want {
+				now = now.Add(time.Minute)
+				entry := h.AggregateHealth(aggregator.WithCheckRound(context.Background()))["api"].(map[string]interface{})
+				if entry["status"] != want {
+					t.Fatalf("check %d status = %v, want %v", i, entry["status"], want)
+				}
+				if i == 0 || tt.want[i-1] != want {
+					since = now
+				}
+				if entry["since"] != since {
+					t.Errorf("check %d since = %v, want %v", i, entry["since"], since)
+				}
+				if (entry["observedStatus"] != nil) == (want == tt.observed[i]) {
+					t.Errorf("check %d observedStatus = %v", i, entry["observedStatus"])
+				}
+			}
+		})
+	}
+}
+
+func TestHysteresis_requests(t *testing.T) {
+	down := aggregatortest.Statuses(map[string]string{"api": aggregator.StatusDown})
+	delegate := &aggregatortest.Stub{Health: aggregatortest.Statuses(map[string]string{"api": aggregator.StatusUp})}
+	h := aggregator.NewHysteresis(delegate, &aggregator.HysteresisConfig{FailureThreshold: 2, SuccessThreshold: 1})
+	round := aggregator.WithCheckRound(context.Background())
+
+	h.AggregateHealth(round)
+	delegate.SetHealth(down)
+	for i := 0; i < 3; i++ {
+		entry := h.AggregateHealth(context.Background())["api"].(map[string]interface{})
+		if entry["status"] != aggregator.StatusUp || entry["observedStatus"] != aggregator.StatusDown {
+			t.Fatalf("request %d entry = %v, want UP observed DOWN", i, entry)
+		}
+	}
+	if entry := h.AggregateHealth(round)["api"].(map[string]interface{}); entry["status"] != aggregator.StatusUp {
+		t.Errorf("first failed round status = %v, want UP", entry["status"])
+	}
+	if entry := h.AggregateHealth(round)["api"].(map[string]interface{}); entry["status"] != aggregator.StatusDown {
+		t.Errorf("second failed round status = %v, want DOWN", entry["status"])
+	}
+}
+
+func TestHysteresis_prune(t *testing.T) {
+	delegate := &aggregatortest.Stub{Script: []map[string]interface{}{
+		aggregatortest.Statuses(map[string]string{"api": aggregator.StatusUp}),
+		{},
+		aggregatortest.Statuses(map[string]string{"api": aggregator.StatusDown}),
+	}}
+	h := aggregator.NewHysteresis(delegate, &aggregator.HysteresisConfig{FailureThreshold: 2, SuccessThreshold: 1})
+	round := aggregator.WithCheckRound(context.Background())
+
+	h.AggregateHealth(round)
+	h.AggregateHealth(round)
+	// the service is tracked anew once it is back
+	if entry := h.AggregateHealth(round)["api"].(map[string]interface{}); entry["status"] != aggregator.StatusDown {
+		t.Errorf("status of the returned service = %v, want DOWN", entry["status"])
+	}
+}
@@ -0,0 +1,30 @@
+package aggregator
+
+// Overall rolls up statuses of the services: MAINTENANCE if any of them is under maintenance,
+// UP if all of them are UP, DOWN if none is or a critical one isn't, DEGRADED otherwise and UNKNOWN if there are none
+func Overall(health map[string]interface{}) string {
+	if len(health) == 0 {
+		return StatusUnknown
+	}
+
+	up, criticalDown := 0, false
+	for _, entry := range health {
+		e, _ := entry.(map[string]interface{})
+		switch e["status"] {
+		case StatusUp:
+			up++
+		case StatusMaintenance:
+			return StatusMaintenance
+		default:
+			criticalDown = criticalDown || IsCritical(entry)
+		}
+	}
+	switch {
+	case up == len(health):
+		return StatusUp
+	case up == 0 || criticalDown:
+		return StatusDown
+	default:
+		return StatusDegraded
+	}
+}
@@ -0,0 +1,33 @@
+package aggregator
+
+import "testing"
+
+func TestOverall(t *testing.T) {
+	up := map[string]interface{}{"status": StatusUp}
+	down := map[string]interface{}{"status": StatusDown}
+	criticalDown := map[string]interface{}{"status": StatusDown, KeyCritical: true}
+
+	tests := []struct {
+		name   string
+		health map[string]interface{}
+		want   string
+	}{
+		{name: "empty", health: map[string]interface{}{}, want: StatusUnknown},
+		{name: "all up", health: map[string]interface{}{"api": up, "uat": up}, want: StatusUp},
+		{name: "some down", health: map[string]interface{}{"api": up, "jobs": down}, want: StatusDegraded},
+		{name: "critical down", health: map[string]interface{}{"api": criticalDown, "jobs": up}, want: StatusDown},
+		{name: "all down", health: map[string]interface{}{"api": down, "jobs": down}, want: StatusDown},
+		{
+			name:   "maintenance",
+			health: map[string]interface{}{"api": criticalDown, "jobs": map[string]interface{}{"status": StatusMaintenance}},
+			want:   StatusMaintenance,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Overall(tt.health); got != tt.want {
+				t.Errorf("Overall() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package aggregator
+
+import "context"
+
+// Payload kinds of composite responses
+const (
+	PayloadInfo   = "info"
+	PayloadHealth = "health"
+)
+
+type payloadKey struct{}
+
+// WithPayload returns context of lookups of services serving the payload kind.
+// Services having no entries of the kind, e.g. dependencies in info, aren't returned by Services
+func WithPayload(ctx context.Context, kind string) context.Context {
+	return context.WithValue(ctx, payloadKey{}, kind)
+}
+
+// PayloadFrom returns the payload kind of the context, empty means any kind
+func PayloadFrom(ctx context.Context) string {
+	kind, _ := ctx.Value(payloadKey{}).(string)
+
+	return kind
+}
@@ -0,0 +1,18 @@
+package aggregator
+
+import "context"
+
+type roundKey struct{}
+
+// WithCheckRound returns context of a background check round.
+// Smoothed statuses advance in check rounds only, other aggregations report them as they are
+func WithCheckRound(ctx context.Context) context.Context {
+	return context.WithValue(ctx, roundKey{}, true)
+}
+
+// IsCheckRound reports whether the context belongs to a background check round
+func IsCheckRound(ctx context.Context) bool {
+	round, _ := ctx.Value(roundKey{}).(bool)
+
+	return round
+}
@@ -0,0 +1,276 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/reportportal/commons-go/v5/server"
+)
+
+// APIKeyHeader carries static API key, it may be passed as a bearer token as well
+const APIKeyHeader = "X-Api-Key"
+
+// Access levels of routes
+const (
+	// LevelUser requires any authenticated principal
+	LevelUser Level = iota + 1
+	// LevelAdmin requires principal having one of the admin roles
+	LevelAdmin
+)
+
+var (
+	errNoCredentials   = errors.New("authentication is required")
+	errIncorrectAPIKey = errors.New("incorrect API key")
+	errForbidden       = errors.New("access is denied")
+	errUnknownKey      = errors.New("unknown signing key")
+	errJWKSTimeout     = errors.New("JWKS timeout must be positive")
+)
+
+// Level is an access level required by a route
+type Level int
+
+// Config holds settings of authentication, it's disabled unless any of API key, JWKS URL or JWT secret is configured
+type Config struct {
+	// APIKeyFile contains static key granting admin access
+	APIKeyFile string `env:"AUTH_API_KEY_FILE" envDefault:""`
+	// JWKSURL is a JWKS endpoint of the authorization service JWT signatures are verified with
+	JWKSURL string `env:"AUTH_JWKS_URL" envDefault:""`
+	// JWKSRefresh is an interval the key set is re-fetched with
+	JWKSRefresh time.Duration `env:"AUTH_JWKS_REFRESH" envDefault:"1h"`
+	// JWKSTimeout bounds a single fetch of the key set
+	JWKSTimeout time.Duration `env:"AUTH_JWKS_TIMEOUT" envDefault:"10s"`
+	// JWTSecretFile contains shared secret of HMAC-signed JWT
+	JWTSecretFile string `env:"AUTH_JWT_SECRET_FILE" envDefault:""`
+	Issuer        string `env:"AUTH_JWT_ISSUER"      envDefault:""`
+	Audience      string `env:"AUTH_JWT_AUDIENCE"    envDefault:""`
+	// RolesClaim is a dot-separated path of JWT claim listing roles of the principal
+	RolesClaim string `env:"AUTH_ROLES_CLAIM" envDefault:"authorities"`
+	// AdminRoles are roles granting admin access
+	AdminRoles []string `env:"AUTH_ADMIN_ROLES" envDefault:"ROLE_ADMINISTRATOR"`
+}
+
+// Principal is an authenticated client
+type Principal struct {
+	Subject string
+	Roles   []string
+	Admin   bool
+}
+
+type principalKey struct{}
+
+// result is an outcome of request authentication stored in its context
+type result struct {
+	principal *Principal
+	err       error
+}
+
+// Authenticator verifies credentials of requests
+type Authenticator struct {
+	cfg    *Config
+	apiKey []byte
+	secret []byte
+	jwks   *keySet
+	parser *jwt.Parser
+}
+
+// New creates authenticator of the config, secrets are read from the configured files
+func New(cfg *Config, httpClient *http.Client) (*Authenticator, error) {
+	a := &Authenticator{cfg: cfg}
+	var err error
+	if a.apiKey, err = readSecret(cfg.APIKeyFile); err != nil {
+		return nil, fmt.Errorf("unable to read API key: %w", err)
+	}
+	if a.secret, err = readSecret(cfg.JWTSecretFile); err != nil {
+		return nil, fmt.Errorf("unable to read JWT secret: %w", err)
+	}
+	if cfg.JWKSURL != "" {
+		if cfg.JWKSTimeout <= 0 {
+			return nil, errJWKSTimeout
+		}
+		a.jwks = newKeySet(cfg.JWKSURL, cfg.JWKSRefresh, cfg.JWKSTimeout, httpClient)
+	}
+
+	var methods []string
+	if len(a.secret) > 0 {
+		methods = append(methods, "HS256", "HS384", "HS512")
+	}
+	if a.jwks != nil {
+		methods = append(methods, "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512")
+	}
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired(), jwt.WithLeeway(30 * time.Second)}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+
+	return a, nil
+}
+
+// Enabled reports whether any credentials are configured
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (len(a.apiKey) > 0 || len(a.secret) > 0 || a.jwks != nil)
+}
+
+// Authenticate identifies the principal of the request, if any. Access is checked by Require
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+		p, err := a.authenticate(r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, &result{principal: p, err: err})))
+	})
+}
+
+// Require rejects requests of principals below the access level
+func (a *Authenticator) Require(level Level) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return server.Handler{H: func(w http.ResponseWriter, r *http.Request) error {
+			if err := a.check(r.Context(), level); err != nil {
+				if errors.Is(err, errForbidden) {
+					return server.ToStatusError(http.StatusForbidden, err)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="service-index"`)
+
+				return server.ToStatusError(http.StatusUnauthorized, err)
+			}
+			next.ServeHTTP(w, r)
+
+			return nil
+		}}
+	}
+}
+
+// Allowed reports whether principal of the context has the access level, everything is allowed with authentication disabled
+func (a *Authenticator) Allowed(ctx context.Context, level Level) bool {
+	return a.check(ctx, level) == nil
+}
+
+func (a *Authenticator) check(ctx context.Context, level Level) error {
+	if !a.Enabled() {
+		return nil
+	}
+	res, _ := ctx.Value(principalKey{}).(*result)
+	switch {
+	case res == nil:
+		return errNoCredentials
+	case res.err != nil:
+		return res.err
+	case level == LevelAdmin && !res.principal.Admin:
+		return errForbidden
+	}
+
+	return nil
+}
+
+// PrincipalFrom returns principal authenticated by the middleware, if any
+func PrincipalFrom(ctx context.Context) *Principal {
+	if res, ok := ctx.Value(principalKey{}).(*result); ok {
+		return res.principal
+	}
+
+	return nil
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*Principal, error) {
+	credentials := r.Header.Get(APIKeyHeader)
+	if credentials == "" {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return nil, errNoCredentials
+		}
+		credentials = strings.TrimSpace(bearer)
+	}
+
+	if len(a.apiKey) > 0 && subtle.ConstantTimeCompare([]byte(credentials), a.apiKey) == 1 {
+		return &Principal{Subject: "api-key", Admin: true}, nil
+	}
+	if len(a.secret) == 0 && a.jwks == nil {
+		return nil, errIncorrectAPIKey
+	}
+
+	return a.parseToken(r.Context(), credentials)
+}
+
+// parseToken validates JWT and extracts principal from its claims
+func (a *Authenticator) parseToken(ctx context.Context, token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+			return a.secret, nil
+		}
+		kid, _ := t.Header["kid"].(string)
+
+		return a.jwks.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("incorrect token: %w", err)
+	}
+
+	p := &Principal{Roles: roles(claims, a.cfg.RolesClaim)}
+	if p.Subject, _ = claims.GetSubject(); p.Subject == "" {
+		p.Subject, _ = claims["user_name"].(string)
+	}
+	for _, role := range a.cfg.AdminRoles {
+		if slices.Contains(p.Roles, role) {
+			p.Admin = true
+
+			break
+		}
+	}
+
+	return p, nil
+}
+
+// roles reads roles claim either listing roles or holding them in a space-separated string
+func roles(claims jwt.MapClaims, path string) []string {
+	var v interface{} = map[string]interface{}(claims)
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+
+	switch rs := v.(type) {
+	case string:
+		return strings.Fields(rs)
+	case []interface{}:
+		res := make([]string, 0, len(rs))
+		for _, r := range rs {
+			if s, ok := r.(string); ok {
+				res = append(res, s)
+			}
+		}
+
+		return res
+	default:
+		return nil
+	}
+}
+
+func readSecret(file string) ([]byte, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.TrimSpace(string(data))), nil
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/reportportal/service-index/internal/testutil"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// writeJWKS writes key set of the only RSA key with ID k1
+func writeJWKS(w http.ResponseWriter, key *rsa.PublicKey) {
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "k1",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+}
+
+func TestAuthenticator_Require(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJWKS(w, &rsaKey.PublicKey)
+	}))
+	defer jwks.Close()
+
+	a, err := New(&Config{
+		APIKeyFile:    testutil.WriteSecret(t, "key", "k3y"),
+		JWTSecretFile: testutil.WriteSecret(t, "secret", "s3cr3t"),
+		JWKSURL:       jwks.URL,
+		JWKSRefresh:   time.Hour,
+		JWKSTimeout:   time.Second,
+		Issuer:        "uat",
+		RolesClaim:    "authorities",
+		AdminRoles:    []string{"ROLE_ADMINISTRATOR"},
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+	admin := jwt.MapClaims{"iss": "uat", "exp": exp, "user_name": "superadmin", "authorities": []string{"ROLE_ADMINISTRATOR"}}
+	user := jwt.MapClaims{"iss": "uat", "exp": exp, "sub": "default", "authorities": []string{"ROLE_USER"}}
+	expired := jwt.MapClaims{"iss": "uat", "exp": time.Now().Add(-time.Hour).Unix(), "authorities": []string{"ROLE_ADMINISTRATOR"}}
+	otherIssuer := jwt.MapClaims{"iss": "other", "exp": exp, "authorities": []string{"ROLE_ADMINISTRATOR"}}
+
+	tests := []struct {
+		name      string
+		header    string
+		value     string
+		wantUser  int
+		wantAdmin int
+	}{
+		{name: "anonymous", wantUser: http.StatusUnauthorized, wantAdmin: http.StatusUnauthorized},
+		{name: "api key", header: APIKeyHeader, value: "k3y", wantUser: http.StatusOK, wantAdmin: http.StatusOK},
+		{name: "api key as bearer", header: "Authorization", value: "Bearer k3y", wantUser: http.StatusOK, wantAdmin: http.StatusOK},
+		{name: "wrong api key", header: APIKeyHeader, value: "wrong", wantUser: http.StatusUnauthorized, wantAdmin: http.StatusUnauthorized},
+		{
+			name:   "shared secret admin",
+			header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("s3cr3t"), "", admin),
+			wantUser: http.StatusOK, wantAdmin: http.StatusOK,
+		},
+		{
+			name:   "shared secret user",
+			header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("s3cr3t"), "", user),
+			wantUser: http.StatusOK, wantAdmin: http.StatusForbidden,
+		},
+		{
+			name:   "wrong secret",
+			header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("wrong"), "", admin),
+			wantUser: http.StatusUnauthorized, wantAdmin: http.StatusUnauthorized,
+		},
+		{
+			name:   "jwks admin",
+			header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodRS256, rsaKey, "k1", admin),
+			wantUser: http.StatusOK, wantAdmin: http.StatusOK,
+		},
+		{
+			name:   "jwks unknown key",
+			header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodRS256, rsaKey, "k2", admin),
+			wantUser: http.StatusUnauthorized, wantAdmin: http.StatusUnauthorized,
+		},
+		{
+			name:   "expired",
+			header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("s3cr3t"), "", expired),
+			wantUser: http.StatusUnauthorized, wantAdmin: http.StatusUnauthorized,
+		},
+		{
+			name:   "other issuer",
+			header: "Authorization", value: "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("s3cr3t"), "", otherIssuer),
+			wantUser: http.StatusUnauthorized, wantAdmin: http.StatusUnauthorized,
+		},
+	}
+
+	ok := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for level, want := range map[Level]int{LevelUser: tt.wantUser, LevelAdmin: tt.wantAdmin} {
+				rq := httptest.NewRequest(http.MethodGet, "/composite/info", nil)
+				if tt.header != "" {
+					rq.Header.Set(tt.header, tt.value)
+				}
+				rr := httptest.NewRecorder()
+				a.Authenticate(a.Require(level)(ok)).ServeHTTP(rr, rq)
+				if rr.Code != want {
+					t.Errorf("Require(%d) code = %d, want %d: %s", level, rr.Code, want, rr.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestKeySet_key(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches int32
+	hung := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// only the first fetch succeeds, the endpoint hangs afterwards
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			select {
+			case <-hung:
+			case <-r.Context().Done():
+			}
+
+			return
+		}
+		writeJWKS(w, &rsaKey.PublicKey)
+	}))
+	defer srv.Close()
+	defer close(hung)
+
+	s := newKeySet(srv.URL, time.Hour, 50*time.Millisecond, http.DefaultClient)
+	if _, err := s.key(context.Background(), "k1"); err != nil {
+		t.Fatalf("key() error = %v", err)
+	}
+
+	// the outdated key is served while the set is re-fetched
+	s.refresh = 0
+	start := time.Now()
+	if _, err := s.key(context.Background(), "k1"); err != nil || time.Since(start) > 25*time.Millisecond {
+		t.Errorf("key() of outdated set error = %v, took %v", err, time.Since(start))
+	}
+
+	// the fetch caused by an unknown key is bounded by the timeout
+	s.mu.Lock()
+	s.fetchedAt = time.Time{}
+	s.mu.Unlock()
+	if _, err := s.key(context.Background(), "k2"); !errors.Is(err, errUnknownKey) {
+		t.Errorf("key() error = %v, want unknown key", err)
+	}
+	if _, err := s.key(context.Background(), "k1"); err != nil {
+		t.Errorf("key() error = %v, keys fetched before must be kept", err)
+	}
+}
+
+func TestAuthenticator_Disabled(t *testing.T) {
+	a, err := New(&Config{}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Enabled() {
+		t.Error("Enabled() = true without credentials")
+	}
+
+	rr := httptest.NewRecorder()
+	a.Authenticate(a.Require(LevelAdmin)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/composite/info", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Require() code = %d, want access with authentication disabled", rr.Code)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	claims := jwt.MapClaims{
+		"scope":        "read write",
+		"realm_access": map[string]interface{}{"roles": []interface{}{"admin", 1}},
+	}
+	if got := roles(claims, "scope"); len(got) != 2 || got[1] != "write" {
+		t.Errorf("roles(scope) = %v", got)
+	}
+	if got := roles(claims, "realm_access.roles"); len(got) != 1 || got[0] != "admin" {
+		t.Errorf("roles(realm_access.roles) = %v", got)
+	}
+	if got := roles(claims, "missing.roles"); got != nil {
+		t.Errorf("roles(missing.roles) = %v", got)
+	}
+}
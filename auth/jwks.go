@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// minRefetch limits re-fetching of the key set caused by tokens signed with unknown keys
+	minRefetch = time.Minute
+	jwksKey    = "jwks"
+)
+
+// jwk is a public key of JWKS document
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet caches public keys of the JWKS endpoint
+type keySet struct {
+	url        string
+	refresh    time.Duration
+	timeout    time.Duration
+	httpClient *http.Client
+	// group shares a single in-flight fetch between concurrent callers
+	group singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+func newKeySet(url string, refresh, timeout time.Duration, httpClient *http.Client) *keySet {
+	return &keySet{url: url, refresh: refresh, timeout: timeout, httpClient: httpClient}
+}
+
+// key returns public key by its ID. Outdated keys are served while the set is re-fetched in background,
+// the caller waits for the set to be re-fetched only if the key is unknown
+func (s *keySet) key(ctx context.Context, kid string) (interface{}, error) {
+	s.mu.Lock()
+	k, ok := s.lookup(kid)
+	since := time.Since(s.fetchedAt)
+	s.mu.Unlock()
+
+	switch {
+	case ok:
+		if since >= s.refresh {
+			s.group.DoChan(jwksKey, s.fetch)
+		}
+
+		return k, nil
+	case since < minRefetch:
+		return nil, errUnknownKey
+	}
+
+	select {
+	case <-s.group.DoChan(jwksKey, s.fetch):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok = s.lookup(kid); !ok {
+		return nil, errUnknownKey
+	}
+
+	return k, nil
+}
+
+// lookup finds the key, the only key of the set matches tokens without key ID
+func (s *keySet) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	k, ok := s.keys[kid]
+
+	return k, ok
+}
+
+// fetch replaces the cached keys with the ones of the endpoint, keys fetched before are kept if it's unavailable
+func (s *keySet) fetch() (interface{}, error) {
+	s.mu.Lock()
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+
+	// the fetch is shared by callers, so it's bounded by its own timeout rather than by a request context
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	keys, err := s.load(ctx)
+	if err != nil {
+		log.Errorf("Unable to fetch JWKS: %v", err)
+
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+
+	return nil, nil
+}
+
+// load fetches and decodes keys of the endpoint
+func (s *keySet) load(ctx context.Context) (map[string]interface{}, error) {
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := s.httpClient.Do(rq)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Body.Close()
+	if rs.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", rs.Status)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(rs.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("unable to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			log.Warnf("Key %s of JWKS is ignored: %v", k.Kid, err)
+
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	return keys, nil
+}
+
+// publicKey decodes RSA or EC public key
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect key parameter: %w", err)
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,113 @@
+package badge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/monitor"
+)
+
+// statusNotFound is a message of badges of unknown services
+const statusNotFound = "not found"
+
+// Config holds settings of status badges
+type Config struct {
+	// Label of the overall badge, badges of services are labeled with service names
+	Label string `env:"BADGE_LABEL" envDefault:"ReportPortal"`
+	// Colors of statuses, either shields.io named colors or hex values, e.g. UP:brightgreen,DOWN:e05d44
+	Colors map[string]string `env:"BADGE_COLORS" envDefault:"UP:brightgreen,DOWN:red,TIMEOUT:red,FLAPPING:orange,MAINTENANCE:blue,DEGRADED:yellow,UNKNOWN:lightgrey"`
+}
+
+// Handler renders SVG badges of the health rollup
+type Handler struct {
+	aggreg aggregator.Aggregator
+	mon    *monitor.Monitor
+	label  string
+	colors map[string]string
+}
+
+// New creates badge handler, health is taken from the monitor snapshot if it's available
+func New(aggreg aggregator.Aggregator, mon *monitor.Monitor, cfg *Config) (*Handler, error) {
+	colors := make(map[string]string, len(cfg.Colors))
+	for status, c := range cfg.Colors {
+		hex, err := parseColor(c)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect badge color of %s: %w", status, err)
+		}
+		colors[strings.ToUpper(status)] = hex
+	}
+
+	return &Handler{aggreg: aggreg, mon: mon, label: cfg.Label, colors: colors}, nil
+}
+
+// ServeHTTP renders badge of the {service} URL parameter or the overall badge, label is overridden by ?label=
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	service := chi.URLParam(r, "service")
+	health, checkedAt, cached := h.health(r.Context(), service)
+
+	label, status, code := h.label, aggregator.Overall(health), http.StatusOK
+	if service != "" {
+		label = service
+		if entry, ok := health[service]; ok {
+			status = monitor.StatusOf(entry)
+		} else {
+			status, code = statusNotFound, http.StatusNotFound
+		}
+	}
+	if l := r.URL.Query().Get("label"); l != "" {
+		label = l
+	}
+
+	svg, err := render(label, strings.ToLower(status), h.color(status))
+	if err != nil {
+		log.Error(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml;charset=utf-8")
+	if cached {
+		// the badge stays fresh till the next check of the monitor
+		age := max(time.Since(checkedAt), 0)
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(h.mon.Interval().Seconds())))
+		w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+		w.Header().Set("Last-Modified", checkedAt.UTC().Format(http.TimeFormat))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.WriteHeader(code)
+	if _, err := w.Write(svg); err != nil {
+		log.Error(err)
+	}
+}
+
+// health returns monitor snapshot if there is one, otherwise aggregates health of the service or all of them
+func (h *Handler) health(ctx context.Context, service string) (map[string]interface{}, time.Time, bool) {
+	if h.mon != nil {
+		if health, checkedAt := h.mon.Snapshot(); !checkedAt.IsZero() {
+			return health, checkedAt, true
+		}
+	}
+	if service != "" {
+		ctx = aggregator.WithFilter(ctx, &aggregator.Filter{Include: []string{service}})
+	}
+
+	return h.aggreg.AggregateHealth(ctx), time.Now(), false
+}
+
+func (h *Handler) color(status string) string {
+	if c, ok := h.colors[status]; ok {
+		return c
+	}
+
+	return namedColors["lightgrey"]
+}
@@ -0,0 +1,115 @@
+package badge
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/aggregator/aggregatortest"
+	"github.com/reportportal/service-index/monitor"
+)
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	aggreg := &aggregatortest.Stub{Health: map[string]interface{}{
+		"api": map[string]interface{}{"status": aggregator.StatusUp},
+		"uat": map[string]interface{}{"status": aggregator.StatusDown},
+	}}
+	h, err := New(aggreg, nil, &Config{Label: "RP", Colors: map[string]string{"UP": "brightgreen", "DOWN": "c00", "DEGRADED": "yellow"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := chi.NewRouter()
+	router.Handle("/badge.svg", h)
+	router.Handle("/badge/{service}.svg", h)
+
+	tests := []struct {
+		name string
+		url  string
+		code int
+		want []string
+	}{
+		{name: "overall", url: "/badge.svg", code: http.StatusOK, want: []string{">RP<", ">degraded<", `fill="#dfb317"`}},
+		{name: "service", url: "/badge/api.svg", code: http.StatusOK, want: []string{">api<", ">up<", `fill="#4c1"`}},
+		{name: "custom label", url: "/badge/uat.svg?label=%3Cuat%3E", code: http.StatusOK, want: []string{">&lt;uat&gt;<", ">down<", `fill="#c00"`}},
+		{name: "unknown service", url: "/badge/jobs.svg", code: http.StatusNotFound, want: []string{">not found<", `fill="#9f9f9f"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rr.Code != tt.code {
+				t.Errorf("ServeHTTP() code = %d, want %d", rr.Code, tt.code)
+			}
+			if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/svg+xml") {
+				t.Errorf("ServeHTTP() content type = %s", ct)
+			}
+			if cc := rr.Header().Get("Cache-Control"); cc != "no-cache" {
+				t.Errorf("ServeHTTP() cache control = %s, want no-cache without monitor", cc)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(rr.Body.String(), want) {
+					t.Errorf("ServeHTTP() body = %s, doesn't contain %s", rr.Body.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_ServeHTTP_Snapshot(t *testing.T) {
+	aggreg := &aggregatortest.Stub{Health: map[string]interface{}{
+		"api": map[string]interface{}{"status": aggregator.StatusUp},
+	}}
+	mon, err := monitor.New(aggreg, &monitor.Config{Interval: time.Minute, Heartbeat: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mon.Check(context.Background())
+
+	h, err := New(aggreg, mon, &Config{Label: "RP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/badge.svg", nil))
+	if cc := rr.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Errorf("ServeHTTP() cache control = %s", cc)
+	}
+	if age := rr.Header().Get("Age"); age != "0" {
+		t.Errorf("ServeHTTP() age = %s", age)
+	}
+	if rr.Header().Get("Last-Modified") == "" {
+		t.Error("ServeHTTP() last modified is missing")
+	}
+}
+
+func TestNew_IncorrectColor(t *testing.T) {
+	if _, err := New(&aggregatortest.Stub{}, nil, &Config{Colors: map[string]string{"UP": "url(#x)"}}); err == nil {
+		t.Error("New() error = nil, want incorrect color")
+	}
+}
+
+func Test_textWidth(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "up", want: 14},
+		{text: "Mil", want: 19},
+		{text: "RP 5", want: 28},
+		{text: "api/v1", want: 38},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := textWidth(tt.text); got != tt.want {
+				t.Errorf("textWidth() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package badge
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"text/template"
+	"unicode"
+)
+
+// named colors of shields.io badges
+var namedColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"grey":        "#555",
+	"lightgrey":   "#9f9f9f",
+}
+
+var hexColor = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+const (
+	// horizontal padding around label and message
+	padding = 6
+	// labelColor is a background of the badge label
+	labelColor = "#555"
+)
+
+var svgTmpl = template.Must(template.New("badge").Parse(
+	`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{html .Label}}: {{html .Message}}">` +
+		`<title>{{html .Label}}: {{html .Message}}</title>` +
+		`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` +
+		`<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>` +
+		`<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="` + labelColor + `"/>` +
+		`<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>` +
+		`<rect width="{{.Width}}" height="20" fill="url(#s)"/></g>` +
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` +
+		`<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Label}}</text>` +
+		`<text x="{{.LabelX}}" y="14">{{html .Label}}</text>` +
+		`<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{html .Message}}</text>` +
+		`<text x="{{.MessageX}}" y="14">{{html .Message}}</text></g></svg>`))
+
+type svgData struct {
+	Label, Message, Color string
+	Width, LabelWidth     int
+	MessageWidth          int
+	LabelX, MessageX      float64
+}
+
+// render produces flat shields-style badge
+func render(label, message, color string) ([]byte, error) {
+	lw, mw := textWidth(label)+2*padding, textWidth(message)+2*padding
+	data := &svgData{
+		Label:        label,
+		Message:      message,
+		Color:        color,
+		Width:        lw + mw,
+		LabelWidth:   lw,
+		MessageWidth: mw,
+		LabelX:       float64(lw) / 2,
+		MessageX:     float64(lw) + float64(mw)/2,
+	}
+
+	var buf bytes.Buffer
+	if err := svgTmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("unable to render badge: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// parseColor resolves either named or hex color
+func parseColor(c string) (string, error) {
+	if hex, ok := namedColors[c]; ok {
+		return hex, nil
+	}
+	if !hexColor.MatchString(c) {
+		return "", fmt.Errorf("incorrect color %q", c)
+	}
+	if c[0] != '#' {
+		c = "#" + c
+	}
+
+	return c, nil
+}
+
+// charWidths are widths of narrow and wide characters rendered with 11px Verdana
+var charWidths = map[rune]int{
+	' ': 4, 'i': 4, 'l': 4, 'j': 4, '.': 4, ',': 4, ':': 4, '|': 4, '!': 4,
+	'f': 5, 't': 5, 'r': 5, 'I': 5, '(': 5, ')': 5, '/': 5, '-': 5,
+	'm': 11, 'w': 11, 'M': 11, 'W': 11,
+}
+
+// textWidth approximates width of the text rendered with 11px Verdana
+func textWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += charWidth(r)
+	}
+
+	return w
+}
+
+func charWidth(r rune) int {
+	if cw, ok := charWidths[r]; ok {
+		return cw
+	}
+	if unicode.IsUpper(r) || unicode.IsDigit(r) {
+		return 8
+	}
+
+	return 7
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config holds settings of response caching
+type Config struct {
+	// TTL is a period composite responses are served from the cache, zero disables caching
+	TTL time.Duration `env:"CACHE_TTL" envDefault:"5s"`
+}
+
+// VariantFunc distinguishes responses of the same resource served to different clients by their credentials.
+// Empty variant means the response is the one of anonymous clients and may be cached by shared caches
+type VariantFunc func(r *http.Request) string
+
+// Cache keeps successful responses for the configured TTL and answers conditional requests
+type Cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	swept   time.Time
+}
+
+type entry struct {
+	contentType string
+	body        []byte
+	etag        string
+	expires     time.Time
+}
+
+// New creates cache of the config
+func New(cfg *Config) *Cache {
+	return &Cache{ttl: cfg.TTL, now: time.Now, entries: map[string]*entry{}}
+}
+
+// Handler serves GET requests from the cache, other requests are passed through.
+// Nil variant means the responses are the same for everybody, otherwise every response varies by credentials
+func (c *Cache) Handler(variant VariantFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c.ttl <= 0 || r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			v := ""
+			if variant != nil {
+				v = variant(r)
+			}
+			key := v + " " + r.URL.RequestURI()
+			if e := c.get(key); e != nil {
+				c.write(w, r, e, v, variant != nil)
+
+				return
+			}
+
+			rec := &recorder{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if rec.status != http.StatusOK {
+				rec.flush(w)
+
+				return
+			}
+			e := &entry{
+				contentType: rec.header.Get("Content-Type"),
+				body:        rec.body.Bytes(),
+				etag:        etagOf(rec.body.Bytes()),
+				expires:     c.now().Add(c.ttl),
+			}
+			c.put(key, e)
+			c.write(w, r, e, v, variant != nil)
+		})
+	}
+}
+
+func (c *Cache) get(key string) *entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expires) {
+		return nil
+	}
+
+	return e
+}
+
+func (c *Cache) put(key string, e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// expired entries are dropped at most once per TTL so that the map doesn't grow with query variations
+	now := c.now()
+	if now.Sub(c.swept) >= c.ttl {
+		c.swept = now
+		for k, old := range c.entries {
+			if !now.Before(old.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = e
+}
+
+// write sends the entry or 304 status if the client has it already.
+// Responses of anonymous clients are public, but they vary by credentials as well as the private ones
+func (c *Cache) write(w http.ResponseWriter, r *http.Request, e *entry, variant string, vary bool) {
+	scope := "private"
+	if variant == "" {
+		scope = "public"
+	}
+	maxAge := int(math.Ceil(e.expires.Sub(c.now()).Seconds()))
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, max(maxAge, 0)))
+	w.Header().Set("ETag", e.etag)
+	if vary {
+		w.Header().Add("Vary", "Authorization, X-Api-Key")
+	}
+	if matchETag(r.Header.Get("If-None-Match"), e.etag) {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+	if e.contentType != "" {
+		w.Header().Set("Content-Type", e.contentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(e.body)
+}
+
+func etagOf(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matchETag reports whether If-None-Match header lists the tag, weak comparison is used as RFC 9110 requires
+func matchETag(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recorder buffers response of the handler
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+// flush sends recorded response as is
+func (r *recorder) flush(w http.ResponseWriter) {
+	for k, v := range r.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(r.status)
+	_, _ = w.Write(r.body.Bytes())
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// backend counts requests passed through the cache, requests with ?fail= parameter fail
+type backend struct {
+	h     http.Handler
+	calls int
+}
+
+// headerVariant distinguishes clients by X-Variant header
+func headerVariant(r *http.Request) string {
+	return r.Header.Get("X-Variant")
+}
+
+func newBackend(c *Cache, variant VariantFunc) *backend {
+	b := &backend{}
+	b.h = c.Handler(variant)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b.calls++
+			if r.URL.Query().Get("fail") != "" {
+				http.Error(w, "failed", http.StatusBadGateway)
+
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"api":{"status":"UP"}}`))
+		}))
+
+	return b
+}
+
+func (b *backend) serve(url string, headers map[string]string) *httptest.ResponseRecorder {
+	rq := httptest.NewRequest(http.MethodGet, url, nil)
+	for k, v := range headers {
+		rq.Header.Set(k, v)
+	}
+	rs := httptest.NewRecorder()
+	b.h.ServeHTTP(rs, rq)
+
+	return rs
+}
+
+func newTestCache() (c *Cache, advance func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c = New(&Config{TTL: 5 * time.Second})
+	c.now = func() time.Time { return now }
+
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCache_Handler(t *testing.T) {
+	c, advance := newTestCache()
+	b := newBackend(c, nil)
+
+	rs := b.serve("/composite/health", nil)
+	if rs.Code != http.StatusOK || rs.Header().Get("ETag") == "" || rs.Header().Get("Cache-Control") != "public, max-age=5" ||
+		rs.Header().Get("Content-Type") != "application/json" || !strings.Contains(rs.Body.String(), "UP") {
+		t.Fatalf("first response = %d %v %s", rs.Code, rs.Header(), rs.Body)
+	}
+
+	advance(2 * time.Second)
+	rs = b.serve("/composite/health", nil)
+	if b.calls != 1 || rs.Code != http.StatusOK || rs.Header().Get("Cache-Control") != "public, max-age=3" {
+		t.Errorf("cached response = %d %v, calls %d", rs.Code, rs.Header(), b.calls)
+	}
+
+	advance(3 * time.Second)
+	b.serve("/composite/health", nil)
+	if b.calls != 2 {
+		t.Errorf("expired response is served, calls %d", b.calls)
+	}
+}
+
+func TestCache_Handler_conditional(t *testing.T) {
+	c, _ := newTestCache()
+	b := newBackend(c, headerVariant)
+
+	etag := b.serve("/composite/health", nil).Header().Get("ETag")
+	rs := b.serve("/composite/health", map[string]string{"If-None-Match": `"other", W/` + etag})
+	if rs.Code != http.StatusNotModified || rs.Body.Len() != 0 {
+		t.Errorf("conditional response = %d %s", rs.Code, rs.Body)
+	}
+}
+
+func TestCache_Handler_noVariant(t *testing.T) {
+	c, _ := newTestCache()
+	b := newBackend(c, nil)
+
+	if rs := b.serve("/composite/health", map[string]string{"X-Variant": "admin"}); rs.Header().Get("Vary") != "" {
+		t.Errorf("response of the same payload for everybody varies by %s", rs.Header().Get("Vary"))
+	}
+}
+
+func TestCache_Handler_variant(t *testing.T) {
+	c, _ := newTestCache()
+	b := newBackend(c, headerVariant)
+
+	// anonymous responses may be cached by shared caches, but not served to clients having credentials
+	rs := b.serve("/composite/health", nil)
+	if rs.Header().Get("Cache-Control") != "public, max-age=5" || rs.Header().Get("Vary") == "" {
+		t.Errorf("anonymous response = %v", rs.Header())
+	}
+	rs = b.serve("/composite/health", map[string]string{"X-Variant": "admin"})
+	if b.calls != 2 || rs.Header().Get("Cache-Control") != "private, max-age=5" || rs.Header().Get("Vary") == "" {
+		t.Errorf("variant response = %v, calls %d", rs.Header(), b.calls)
+	}
+}
+
+func TestCache_Handler_failure(t *testing.T) {
+	c, _ := newTestCache()
+	b := newBackend(c, headerVariant)
+
+	for i := 0; i < 2; i++ {
+		if rs := b.serve("/composite/health?fail=1", nil); rs.Code != http.StatusBadGateway || rs.Header().Get("ETag") != "" {
+			t.Errorf("failed response = %d %v", rs.Code, rs.Header())
+		}
+	}
+	if b.calls != 2 {
+		t.Errorf("failed responses are cached, calls %d", b.calls)
+	}
+}
+
+func TestCache_disabled(t *testing.T) {
+	calls := 0
+	h := New(&Config{}).Handler(nil)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+	}))
+	for i := 0; i < 2; i++ {
+		rs := httptest.NewRecorder()
+		h.ServeHTTP(rs, httptest.NewRequest(http.MethodGet, "/composite/health", nil))
+		if rs.Header().Get("ETag") != "" {
+			t.Errorf("disabled cache sets ETag")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("disabled cache serves cached responses, calls %d", calls)
+	}
+}
@@ -0,0 +1,221 @@
+package compat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/probe"
+)
+
+// Config holds compatibility check settings
+type Config struct {
+	// File is an optional path to YAML or JSON compatibility matrix, built-in one is used if empty
+	File string `env:"COMPATIBILITY_FILE" envDefault:""`
+	// Health includes compatibility entry into composite health
+	Health bool `env:"COMPATIBILITY_HEALTH" envDefault:"false"`
+	// InfoTTL is how long versions collected for the health entry are reused
+	InfoTTL time.Duration `env:"COMPATIBILITY_INFO_TTL" envDefault:"1m"`
+}
+
+// entryName is a name of compatibility entry in composite health
+const entryName = "compatibility"
+
+// Report is a result of compatibility matrix evaluation
+type Report struct {
+	Compatible bool              `json:"compatible"`
+	Versions   map[string]string `json:"versions"`
+	Violations []Violation       `json:"violations,omitempty"`
+}
+
+// Violation describes incompatible components
+type Violation struct {
+	Services []string `json:"services"`
+	Message  string   `json:"message"`
+}
+
+// Evaluate checks versions reported by composite info against the matrix.
+// Services which didn't report a version are skipped
+func (m *Matrix) Evaluate(info map[string]interface{}) *Report {
+	versions := buildVersions(info)
+	rp := &Report{Compatible: true, Versions: versions}
+
+	for _, g := range m.Groups {
+		if v := m.checkGroup(g, versions); v != nil {
+			rp.Violations = append(rp.Violations, *v)
+		}
+	}
+	for _, r := range m.Rules {
+		rp.Violations = append(rp.Violations, m.checkRule(r, versions)...)
+	}
+	rp.Compatible = len(rp.Violations) == 0
+
+	return rp
+}
+
+func (m *Matrix) checkGroup(g *Group, versions map[string]string) *Violation {
+	n := matchLen(g.Match)
+	var services []string
+	var ref probe.Version
+	mismatch := false
+	for _, s := range g.Services {
+		v, err := probe.ParseVersion(versions[s])
+		if err != nil {
+			continue
+		}
+		services = append(services, s)
+		if len(v) > n {
+			v = v[:n]
+		}
+		if ref == nil {
+			ref = v
+		} else if ref.Compare(v) != 0 {
+			mismatch = true
+		}
+	}
+	if !mismatch {
+		return nil
+	}
+
+	reported := make([]string, 0, len(services))
+	for _, s := range services {
+		reported = append(reported, s+" "+versions[s])
+	}
+
+	return &Violation{
+		Services: services,
+		Message:  fmt.Sprintf("%s versions must match: %s", g.Match, strings.Join(reported, ", ")),
+	}
+}
+
+func (m *Matrix) checkRule(r *Rule, versions map[string]string) []Violation {
+	v, err := probe.ParseVersion(versions[r.Service])
+	if err != nil || (r.versions != nil && !r.versions.Check(v)) {
+		return nil
+	}
+
+	deps := make([]string, 0, len(r.requires))
+	for dep := range r.requires {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+
+	var violations []Violation
+	for _, dep := range deps {
+		dv, err := probe.ParseVersion(versions[dep])
+		if err != nil {
+			continue
+		}
+		if c := r.requires[dep]; !c.Check(dv) {
+			violations = append(violations, Violation{
+				Services: []string{r.Service, dep},
+				Message: fmt.Sprintf("%s %s requires %s %s, found %s",
+					r.Service, versions[r.Service], dep, c, versions[dep]),
+			})
+		}
+	}
+
+	return violations
+}
+
+// buildVersions extracts build.version of services from composite info
+func buildVersions(info map[string]interface{}) map[string]string {
+	versions := make(map[string]string, len(info))
+	for name, rs := range info {
+		body, _ := rs.(map[string]interface{})
+		build, _ := body["build"].(map[string]interface{})
+		if v, _ := build["version"].(string); v != "" {
+			versions[name] = v
+		}
+	}
+
+	return versions
+}
+
+// Aggregator includes result of compatibility check into composite health
+type Aggregator struct {
+	delegate aggregator.Aggregator
+	matrix   *Matrix
+	infoTTL  time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	info    map[string]interface{}
+	expires time.Time
+}
+
+// NewAggregator wraps the aggregator with compatibility check.
+// Versions are collected at most once per infoTTL since they change on deployments only
+func NewAggregator(delegate aggregator.Aggregator, matrix *Matrix, infoTTL time.Duration) *Aggregator {
+	return &Aggregator{delegate: delegate, matrix: matrix, infoTTL: infoTTL, now: time.Now}
+}
+
+// AggregateInfo collects information from info endpoints
+func (a *Aggregator) AggregateInfo(ctx context.Context) map[string]interface{} {
+	return a.delegate.AggregateInfo(ctx)
+}
+
+// Services returns names of discovered services along with the compatibility entry of composite health
+func (a *Aggregator) Services(ctx context.Context) ([]string, error) {
+	services, err := a.delegate.Services(ctx)
+	if err != nil || aggregator.PayloadFrom(ctx) == aggregator.PayloadInfo {
+		return services, err
+	}
+
+	return append(services, entryName), nil
+}
+
+// Discover resolves nodes and describes the result along with the ignored objects
+func (a *Aggregator) Discover(ctx context.Context) *aggregator.Discovery {
+	return a.delegate.Discover(ctx)
+}
+
+// AggregateHealth aggregates health of services along with compatibility of their versions
+func (a *Aggregator) AggregateHealth(ctx context.Context) map[string]interface{} {
+	if !aggregator.FilterFrom(ctx).Match(entryName) {
+		return a.delegate.AggregateHealth(ctx)
+	}
+
+	info := make(chan map[string]interface{}, 1)
+	go func() {
+		info <- a.versionsInfo(ctx)
+	}()
+
+	health := a.delegate.AggregateHealth(ctx)
+	rp := a.matrix.Evaluate(<-info)
+	entry := map[string]interface{}{"status": aggregator.StatusUp}
+	if !rp.Compatible {
+		entry["status"] = aggregator.StatusDown
+		entry["violations"] = rp.Violations
+	}
+	health[entryName] = entry
+
+	return health
+}
+
+// versionsInfo returns composite info of all the services, the result is reused until it expires
+func (a *Aggregator) versionsInfo(ctx context.Context) map[string]interface{} {
+	a.mu.Lock()
+	if a.info != nil && a.now().Before(a.expires) {
+		defer a.mu.Unlock()
+
+		return a.info
+	}
+	a.mu.Unlock()
+
+	// compatibility depends on versions of all the services regardless of the filter
+	info := a.delegate.AggregateInfo(aggregator.WithFilter(ctx, nil))
+	if len(info) == 0 {
+		return info
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.info, a.expires = info, a.now().Add(a.infoTTL)
+
+	return info
+}
@@ -0,0 +1,153 @@
+package compat
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/aggregator/aggregatortest"
+)
+
+func info(versions map[string]string) map[string]interface{} {
+	rs := make(map[string]interface{}, len(versions))
+	for name, v := range versions {
+		rs[name] = map[string]interface{}{"build": map[string]interface{}{"version": v}}
+	}
+
+	return rs
+}
+
+func TestMatrix_Evaluate(t *testing.T) {
+	m, err := ParseMatrix([]byte(`
+groups:
+  - services: [api, uat, ui, jobs]
+rules:
+  - service: api
+    versions: ">=5.11.0"
+    requires:
+      analyzer: ">=5.11.0 <6"
+`))
+	if err != nil {
+		t.Fatalf("ParseMatrix() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		versions map[string]string
+		want     int
+	}{
+		{
+			name:     "aligned",
+			versions: map[string]string{"api": "5.11.2", "uat": "5.11.0", "ui": "5.11.1", "analyzer": "5.11.0-r1"},
+		},
+		{
+			name:     "ui lags behind",
+			versions: map[string]string{"api": "5.11.2", "uat": "5.11.0", "ui": "5.10.1"},
+			want:     1,
+		},
+		{
+			name:     "outdated analyzer",
+			versions: map[string]string{"api": "5.11.0", "analyzer": "5.10.0"},
+			want:     1,
+		},
+		{
+			name:     "rule doesn't apply",
+			versions: map[string]string{"api": "5.10.0", "analyzer": "5.9.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := m.Evaluate(info(tt.versions))
+			if len(rp.Violations) != tt.want || rp.Compatible != (tt.want == 0) {
+				t.Errorf("Evaluate() = %+v, want %d violations", rp, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMatrix_Default(t *testing.T) {
+	m, err := LoadMatrix("")
+	if err != nil {
+		t.Fatalf("LoadMatrix() error = %v", err)
+	}
+	if rp := m.Evaluate(info(map[string]string{"api": "5.11.0", "jobs": "5.10.0"})); rp.Compatible {
+		t.Errorf("Evaluate() = %+v, want incompatible", rp)
+	}
+
+	if _, err := ParseMatrix([]byte("rules:\n  - service: api\n    requires:\n      ui: \">=x\"\n")); err == nil {
+		t.Error("ParseMatrix() expected error for incorrect constraint")
+	}
+}
+
+func TestAggregator_AggregateHealth(t *testing.T) {
+	m, err := LoadMatrix("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delegate := &aggregatortest.Stub{
+		Info:   info(map[string]string{"api": "5.11.0", "ui": "5.12.0"}),
+		Health: aggregatortest.Statuses(map[string]string{"api": aggregator.StatusUp}),
+	}
+
+	health := NewAggregator(delegate, m, time.Minute).AggregateHealth(context.Background())
+	entry, _ := health[entryName].(map[string]interface{})
+	if entry["status"] != aggregator.StatusDown {
+		t.Errorf("AggregateHealth() compatibility = %v, want %s", entry, aggregator.StatusDown)
+	}
+	if _, ok := health["api"]; !ok {
+		t.Error("AggregateHealth() lost service entries")
+	}
+}
+
+func TestAggregator_versionsInfo(t *testing.T) {
+	m, err := LoadMatrix("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	delegate := &aggregatortest.Stub{Info: info(map[string]string{"api": "5.11.0"})}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := NewAggregator(delegate, m, time.Minute)
+	a.now = func() time.Time { return now }
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		calls int
+	}{
+		{name: "first check", calls: 1},
+		{name: "cached", after: 30 * time.Second, calls: 1},
+		{name: "expired", after: time.Minute, calls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.after)
+			a.AggregateHealth(context.Background())
+			if calls := delegate.InfoCalls(); calls != tt.calls {
+				t.Errorf("AggregateHealth() collected info %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestAggregator_Services(t *testing.T) {
+	a := NewAggregator(&aggregatortest.Stub{Health: map[string]interface{}{"api": nil}}, &Matrix{}, time.Minute)
+
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{name: "health", payload: aggregator.PayloadHealth, want: []string{"api", entryName}},
+		{name: "info", payload: aggregator.PayloadInfo, want: []string{"api"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Services(aggregator.WithPayload(context.Background(), tt.payload))
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Services() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reportportal/service-index/probe"
+)
+
+// Constraint is a set of version comparisons all of which must hold, e.g. ">=5.11.0 <6.0.0"
+type Constraint struct {
+	raw   string
+	terms []term
+}
+
+type term struct {
+	op string
+	v  probe.Version
+}
+
+// comparison operators ordered so that longer ones are matched first
+var operators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses space-separated comparisons, version without an operator means equality
+func ParseConstraint(s string) (*Constraint, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+
+	c := &Constraint{raw: s, terms: make([]term, 0, len(fields))}
+	for _, f := range fields {
+		op := "="
+		for _, o := range operators {
+			if strings.HasPrefix(f, o) {
+				op = o
+				f = f[len(o):]
+
+				break
+			}
+		}
+		v, err := probe.ParseVersion(f)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect version constraint %q: %w", s, err)
+		}
+		c.terms = append(c.terms, term{op: op, v: v})
+	}
+
+	return c, nil
+}
+
+// Check reports whether the version satisfies the constraint
+func (c *Constraint) Check(v probe.Version) bool {
+	for _, t := range c.terms {
+		cmp := v.Compare(t.v)
+		var ok bool
+		switch t.op {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
@@ -0,0 +1,109 @@
+package compat
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Group match levels
+const (
+	MatchMajor = "major"
+	MatchMinor = "minor"
+	MatchPatch = "patch"
+)
+
+//go:embed default.yaml
+var defaultMatrix []byte
+
+// Matrix declares version compatibility of the components
+type Matrix struct {
+	// Groups list components which must run the same version
+	Groups []*Group `json:"groups,omitempty"`
+	// Rules constrain versions of components depending on a version of another one
+	Rules []*Rule `json:"rules,omitempty"`
+}
+
+// Group is a set of services released together
+type Group struct {
+	Services []string `json:"services"`
+	// Match is a number of version components which must be equal: major, minor or patch
+	Match string `json:"match,omitempty"`
+}
+
+// Rule requires versions of the dependencies if version of the service matches
+type Rule struct {
+	Service string `json:"service"`
+	// Versions limits the rule to versions of the service, empty means any version
+	Versions string `json:"versions,omitempty"`
+	// Requires maps dependency names to constraints of their versions
+	Requires map[string]string `json:"requires"`
+
+	versions *Constraint
+	requires map[string]*Constraint
+}
+
+// LoadMatrix reads the matrix from the file, the built-in one is used if the file isn't set
+func LoadMatrix(file string) (*Matrix, error) {
+	data := defaultMatrix
+	if file != "" {
+		var err error
+		if data, err = os.ReadFile(file); err != nil {
+			return nil, fmt.Errorf("unable to read compatibility matrix: %w", err)
+		}
+	}
+
+	return ParseMatrix(data)
+}
+
+// ParseMatrix parses YAML or JSON matrix and checks it is correct
+func ParseMatrix(data []byte) (*Matrix, error) {
+	var m Matrix
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		return nil, fmt.Errorf("unable to parse compatibility matrix: %w", err)
+	}
+
+	for _, g := range m.Groups {
+		if g.Match == "" {
+			g.Match = MatchMinor
+		}
+		if matchLen(g.Match) == 0 {
+			return nil, fmt.Errorf("incorrect match %q of group %v", g.Match, g.Services)
+		}
+	}
+	for _, r := range m.Rules {
+		if r.Versions != "" {
+			c, err := ParseConstraint(r.Versions)
+			if err != nil {
+				return nil, fmt.Errorf("incorrect rule of %s: %w", r.Service, err)
+			}
+			r.versions = c
+		}
+		r.requires = make(map[string]*Constraint, len(r.Requires))
+		for dep, s := range r.Requires {
+			c, err := ParseConstraint(s)
+			if err != nil {
+				return nil, fmt.Errorf("incorrect rule of %s: %w", r.Service, err)
+			}
+			r.requires[dep] = c
+		}
+	}
+
+	return &m, nil
+}
+
+// matchLen returns number of version components compared by the match level
+func matchLen(match string) int {
+	switch match {
+	case MatchMajor:
+		return 1
+	case MatchMinor:
+		return 2
+	case MatchPatch:
+		return 3
+	default:
+		return 0
+	}
+}
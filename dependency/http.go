@@ -0,0 +1,142 @@
+package dependency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const opensearchRed = "red"
+
+var (
+	errUnexpectedStatus = errors.New("unexpected response status")
+	errClusterRed       = errors.New("cluster status is red")
+	errBucketNotFound   = errors.New("bucket not found")
+)
+
+// RabbitMQ checks RabbitMQ through its management API
+type RabbitMQ struct {
+	url string
+	c   *http.Client
+}
+
+// Name is a name of the dependency entry in composite health
+func (r *RabbitMQ) Name() string {
+	return "rabbitmq"
+}
+
+// Check verifies the broker has no resource alarms in effect
+func (r *RabbitMQ) Check(ctx context.Context) error {
+	rs, err := send(ctx, r.c, http.MethodGet, r.url, "/api/health/checks/alarms")
+	if err != nil {
+		return err
+	}
+	defer rs.Body.Close()
+
+	return expectStatus(rs, http.StatusOK)
+}
+
+// OpenSearch checks OpenSearch cluster health
+type OpenSearch struct {
+	url string
+	c   *http.Client
+}
+
+// Name is a name of the dependency entry in composite health
+func (o *OpenSearch) Name() string {
+	return "opensearch"
+}
+
+// Check verifies cluster status isn't red
+func (o *OpenSearch) Check(ctx context.Context) error {
+	rs, err := send(ctx, o.c, http.MethodGet, o.url, "/_cluster/health")
+	if err != nil {
+		return err
+	}
+	defer rs.Body.Close()
+
+	if err := expectStatus(rs, http.StatusOK); err != nil {
+		return err
+	}
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(rs.Body).Decode(&health); err != nil {
+		return fmt.Errorf("unable to decode cluster health: %w", err)
+	}
+	if health.Status == opensearchRed {
+		return errClusterRed
+	}
+
+	return nil
+}
+
+// MinIO checks reachability of a MinIO or S3 bucket
+type MinIO struct {
+	url string
+	c   *http.Client
+}
+
+// Name is a name of the dependency entry in composite health
+func (m *MinIO) Name() string {
+	return "minio"
+}
+
+// Check sends HEAD request to the bucket.
+// Forbidden response means the bucket exists, but anonymous access is denied, so it is treated as reachable
+func (m *MinIO) Check(ctx context.Context) error {
+	rs, err := send(ctx, m.c, http.MethodHead, m.url, "")
+	if err != nil {
+		return err
+	}
+	defer rs.Body.Close()
+
+	if rs.StatusCode == http.StatusNotFound {
+		return errBucketNotFound
+	}
+
+	return expectStatus(rs, http.StatusOK, http.StatusForbidden)
+}
+
+// send sends request to the path relative to base URL, credentials of the base URL are sent as basic auth
+func send(ctx context.Context, c *http.Client, method, base, path string) (*http.Response, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect URL: %w", err)
+	}
+	user := u.User
+	u.User = nil
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+
+	rq, err := http.NewRequestWithContext(ctx, method, u.String(), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	if user != nil {
+		password, _ := user.Password()
+		rq.SetBasicAuth(user.Username(), password)
+	}
+
+	rs, err := c.Do(rq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	return rs, nil
+}
+
+func expectStatus(rs *http.Response, codes ...int) error {
+	for _, code := range codes {
+		if rs.StatusCode == code {
+			return nil
+		}
+	}
+	body, _ := io.ReadAll(io.LimitReader(rs.Body, 512))
+
+	return fmt.Errorf("%w %d: %s", errUnexpectedStatus, rs.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,52 @@
+package dependency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPCheckers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		switch rq.URL.Path {
+		case "/api/health/checks/alarms":
+			if user, pass, ok := rq.BasicAuth(); !ok || user != "rabbitmq" || pass != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+
+				return
+			}
+			_, _ = w.Write([]byte(`{"status":"ok"}`))
+		case "/green/_cluster/health":
+			_, _ = w.Write([]byte(`{"status":"green"}`))
+		case "/red/_cluster/health":
+			_, _ = w.Write([]byte(`{"status":"red"}`))
+		case "/private-bucket":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		checker Checker
+		wantErr bool
+	}{
+		{name: "rabbitmq", checker: &RabbitMQ{url: "http://rabbitmq:secret@" + srv.Listener.Addr().String(), c: srv.Client()}},
+		{name: "rabbitmq unauthorized", checker: &RabbitMQ{url: srv.URL, c: srv.Client()}, wantErr: true},
+		{name: "opensearch green", checker: &OpenSearch{url: srv.URL + "/green", c: srv.Client()}},
+		{name: "opensearch red", checker: &OpenSearch{url: srv.URL + "/red/", c: srv.Client()}, wantErr: true},
+		{name: "minio private bucket", checker: &MinIO{url: srv.URL + "/private-bucket", c: srv.Client()}},
+		{name: "minio missing bucket", checker: &MinIO{url: srv.URL + "/missing", c: srv.Client()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.checker.Check(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
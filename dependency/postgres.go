@@ -0,0 +1,56 @@
+package dependency
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+const (
+	// postgresPingTimeout limits a single check regardless of the health timeout
+	postgresPingTimeout = 5 * time.Second
+	postgresMaxConns    = 2
+	postgresMaxIdleTime = 5 * time.Minute
+)
+
+// Postgres checks PostgreSQL connection with a trivial query.
+// Connections are pooled, so checks don't open a new connection each time
+type Postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres creates checker of the database, no connection is established until the first check
+func NewPostgres(url string) (*Postgres, error) {
+	cfg, err := pgx.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect PostgreSQL URL: %w", err)
+	}
+	db := stdlib.OpenDB(*cfg)
+	db.SetMaxOpenConns(postgresMaxConns)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxIdleTime(postgresMaxIdleTime)
+
+	return &Postgres{db: db}, nil
+}
+
+// Name is a name of the dependency entry in composite health
+func (p *Postgres) Name() string {
+	return "postgres"
+}
+
+// Check runs SELECT 1 using a pooled connection
+func (p *Postgres) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, postgresPingTimeout)
+	defer cancel()
+
+	var one int
+	if err := p.db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("unable to execute query: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/reportportal/commons-go/v5/server"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/auth"
+	"github.com/reportportal/service-index/cache"
+	"github.com/reportportal/service-index/monitor"
+)
+
+// aggregateFunc is either AggregateInfo or AggregateHealth of an aggregator
+type aggregateFunc func(aggregator.Aggregator, context.Context) map[string]interface{}
+
+// compositeHandler serves composite response of the payload kind limited to the services selected by the request.
+// Single service is selected by the {service} URL parameter, otherwise ?services= and ?exclude= lists are applied
+func compositeHandler(aggreg aggregator.Aggregator, payload string, aggregate aggregateFunc) http.Handler {
+	return server.Handler{H: func(w http.ResponseWriter, r *http.Request) error {
+		service := chi.URLParam(r, "service")
+		filter := aggregator.ParseFilter(r.URL.Query().Get("services"), r.URL.Query().Get("exclude"))
+		if service != "" {
+			filter = &aggregator.Filter{Include: []string{service}}
+		}
+
+		if filter != nil && len(filter.Include) > 0 {
+			known, err := aggreg.Services(aggregator.WithPayload(r.Context(), payload))
+			if nil != err {
+				return server.ToStatusError(http.StatusBadGateway, fmt.Errorf("unable to discover services: %w", err))
+			}
+			if unknown := filter.Unknown(known); len(unknown) > 0 {
+				return server.ToStatusError(http.StatusNotFound, fmt.Errorf("unknown services: %s", strings.Join(unknown, ", ")))
+			}
+		}
+
+		res := aggregate(aggreg, aggregator.WithFilter(r.Context(), filter))
+		if service == "" {
+			return server.WriteJSON(http.StatusOK, res, w)
+		}
+
+		entry, ok := res[service]
+		if !ok {
+			return server.ToStatusError(http.StatusServiceUnavailable, fmt.Errorf("service %s didn't respond", service))
+		}
+
+		return server.WriteJSON(http.StatusOK, entry, w)
+	}}
+}
+
+// plainHealth aggregates health reduced to statuses of services unless the client is authenticated
+func plainHealth(authn *auth.Authenticator) aggregateFunc {
+	return func(aggreg aggregator.Aggregator, ctx context.Context) map[string]interface{} {
+		health := aggreg.AggregateHealth(ctx)
+		if authn.Allowed(ctx, auth.LevelUser) {
+			return health
+		}
+
+		for name, entry := range health {
+			health[name] = map[string]interface{}{"status": monitor.StatusOf(entry)}
+		}
+
+		return health
+	}
+}
+
+// cacheVariant separates cached responses by access level of clients since they see different payloads,
+// nil is returned if authentication is disabled and everybody sees the same payloads
+func cacheVariant(authn *auth.Authenticator) cache.VariantFunc {
+	if !authn.Enabled() {
+		return nil
+	}
+
+	return func(r *http.Request) string {
+		switch {
+		case authn.Allowed(r.Context(), auth.LevelAdmin):
+			return "admin"
+		case authn.Allowed(r.Context(), auth.LevelUser):
+			return "user"
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package history
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/reportportal/commons-go/v5/server"
+)
+
+var errIncorrectPeriod = errors.New("'from' must precede 'to'")
+
+// ServeHTTP reports history of services, the period is set by RFC3339 'from' and 'to' parameters
+func (h *History) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.Handler{H: func(w http.ResponseWriter, r *http.Request) error {
+		q := r.URL.Query()
+		from, to, err := h.parsePeriod(q.Get("from"), q.Get("to"))
+		if err != nil {
+			return server.ToStatusError(http.StatusBadRequest, err)
+		}
+
+		return server.WriteJSON(http.StatusOK, h.Query(q.Get("service"), from, to), w)
+	}}.ServeHTTP(w, r)
+}
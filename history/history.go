@@ -0,0 +1,260 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/monitor"
+)
+
+// Config holds settings of health history and SLO computation
+type Config struct {
+	// Size is a number of status transitions kept per service
+	Size int `env:"HISTORY_SIZE" envDefault:"1000"`
+	// File is an optional path to bbolt database persisting history between restarts
+	File string `env:"HISTORY_FILE" envDefault:""`
+	// SLOTarget is a default availability target in percents
+	SLOTarget float64 `env:"SLO_TARGET" envDefault:"99.9"`
+	// SLOTargets overrides availability targets of services, e.g. api:99.95,analyzer:99
+	SLOTargets map[string]float64 `env:"SLO_TARGETS"`
+	// Window is a default period of history queries and SLO computation
+	Window time.Duration `env:"SLO_WINDOW" envDefault:"720h"`
+}
+
+// Transition is a change of service status
+type Transition struct {
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+}
+
+// Report describes history of services within the period
+type Report struct {
+	From     time.Time                 `json:"from"`
+	To       time.Time                 `json:"to"`
+	Services map[string]*ServiceReport `json:"services"`
+}
+
+// ServiceReport describes history of a single service
+type ServiceReport struct {
+	Transitions []Transition `json:"transitions"`
+	// Uptime is a percentage of time the service was UP, periods of UNKNOWN and MAINTENANCE statuses aren't taken into account
+	Uptime *float64 `json:"uptime,omitempty"`
+	SLO    *SLO     `json:"slo,omitempty"`
+}
+
+// SLO describes error budget of the service within the period
+type SLO struct {
+	Target float64 `json:"target"`
+	// BudgetSeconds is an allowed downtime
+	BudgetSeconds float64 `json:"budgetSeconds"`
+	// DowntimeSeconds is an actual downtime
+	DowntimeSeconds float64 `json:"downtimeSeconds"`
+	// Burn is a consumed part of error budget, values above 1 mean the target is missed
+	Burn float64 `json:"burn"`
+}
+
+// History keeps rolling history of status transitions of services
+type History struct {
+	cfg   *Config
+	store *store
+	now   func() time.Time
+
+	mu          sync.RWMutex
+	transitions map[string][]Transition
+}
+
+// New creates history, transitions persisted in the configured file are loaded
+func New(cfg *Config) (*History, error) {
+	h := &History{cfg: cfg, now: time.Now, transitions: map[string][]Transition{}}
+	if cfg.File == "" {
+		return h, nil
+	}
+
+	st, err := openStore(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := st.load()
+	if err != nil {
+		return nil, err
+	}
+	h.store = st
+	for service, ts := range loaded {
+		if len(ts) > cfg.Size {
+			if err := st.trim(service, ts[:len(ts)-cfg.Size]); err != nil {
+				return nil, err
+			}
+			ts = ts[len(ts)-cfg.Size:]
+		}
+		h.transitions[service] = ts
+	}
+
+	return h, nil
+}
+
+// Close releases the underlying storage
+func (h *History) Close() error {
+	if h.store == nil {
+		return nil
+	}
+
+	return h.store.close()
+}
+
+// Observe records status changes reported by the monitor
+func (h *History) Observe(ev monitor.Event) {
+	for service, entry := range ev.Services {
+		h.Record(service, Transition{Time: ev.Time, Status: monitor.StatusOf(entry)})
+	}
+}
+
+// Record appends transition of the service unless the status stays the same
+func (h *History) Record(service string, t Transition) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ts := h.transitions[service]
+	if len(ts) > 0 && ts[len(ts)-1].Status == t.Status {
+		return
+	}
+	ts = append(ts, t)
+
+	var dropped []Transition
+	if len(ts) > h.cfg.Size {
+		dropped = ts[:len(ts)-h.cfg.Size]
+		ts = append([]Transition(nil), ts[len(ts)-h.cfg.Size:]...)
+	}
+	h.transitions[service] = ts
+
+	if h.store == nil {
+		return
+	}
+	if err := h.store.append(service, t); err != nil {
+		log.Errorf("Unable to persist history of %s: %v", service, err)
+	}
+	if err := h.store.trim(service, dropped); err != nil {
+		log.Errorf("Unable to trim history of %s: %v", service, err)
+	}
+}
+
+// Query reports transitions, uptime and SLO of the services within the period, empty service means all of them
+func (h *History) Query(service string, from, to time.Time) *Report {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	rp := &Report{From: from, To: to, Services: map[string]*ServiceReport{}}
+	for name, ts := range h.transitions {
+		if service != "" && name != service {
+			continue
+		}
+		rp.Services[name] = h.serviceReport(name, ts, from, to)
+	}
+
+	return rp
+}
+
+func (h *History) serviceReport(service string, ts []Transition, from, to time.Time) *ServiceReport {
+	sr := &ServiceReport{Transitions: []Transition{}}
+	for _, t := range ts {
+		if !t.Time.Before(from) && !t.Time.After(to) {
+			sr.Transitions = append(sr.Transitions, t)
+		}
+	}
+
+	// the future part of the period isn't accounted
+	if now := h.now(); to.After(now) {
+		to = now
+	}
+	up, down := durations(ts, from, to)
+	if up+down == 0 {
+		return sr
+	}
+
+	uptime := 100 * float64(up) / float64(up+down)
+	sr.Uptime = &uptime
+
+	target := h.target(service)
+	slo := &SLO{
+		Target:          target,
+		BudgetSeconds:   (1 - target/100) * (up + down).Seconds(),
+		DowntimeSeconds: down.Seconds(),
+	}
+	if slo.BudgetSeconds > 0 {
+		slo.Burn = slo.DowntimeSeconds / slo.BudgetSeconds
+	}
+	sr.SLO = slo
+
+	return sr
+}
+
+// target returns availability target of the service in percents
+func (h *History) target(service string) float64 {
+	if t, ok := h.cfg.SLOTargets[service]; ok {
+		return t
+	}
+
+	return h.cfg.SLOTarget
+}
+
+// durations sums up time the service spent UP and DOWN within the period.
+// Status lasts till the next transition, the last one lasts till the end of the period
+func durations(ts []Transition, from, to time.Time) (up, down time.Duration) {
+	// transitions are appended in order of checks, but persisted ones are sorted just in case
+	i := sort.Search(len(ts), func(i int) bool { return ts[i].Time.After(from) })
+	for j := max(i-1, 0); j < len(ts); j++ {
+		start := ts[j].Time
+		if start.After(to) {
+			break
+		}
+		if start.Before(from) {
+			start = from
+		}
+		end := to
+		if j+1 < len(ts) && ts[j+1].Time.Before(to) {
+			end = ts[j+1].Time
+		}
+		if !end.After(start) {
+			continue
+		}
+
+		switch ts[j].Status {
+		case aggregator.StatusUp:
+			up += end.Sub(start)
+		case aggregator.StatusUnknown, aggregator.StatusMaintenance:
+		default:
+			down += end.Sub(start)
+		}
+	}
+
+	return up, down
+}
+
+// parsePeriod parses from and to bounds of the query, the window before now is used by default
+func (h *History) parsePeriod(fromS, toS string) (time.Time, time.Time, error) {
+	to := h.now()
+	if toS != "" {
+		t, err := time.Parse(time.RFC3339, toS)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("incorrect 'to' parameter: %w", err)
+		}
+		to = t
+	}
+	from := to.Add(-h.cfg.Window)
+	if fromS != "" {
+		t, err := time.Parse(time.RFC3339, fromS)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("incorrect 'from' parameter: %w", err)
+		}
+		from = t
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errIncorrectPeriod
+	}
+
+	return from, to, nil
+}
@@ -0,0 +1,102 @@
+package history
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/monitor"
+)
+
+func TestHistory_Query(t *testing.T) {
+	h, err := New(&Config{Size: 10, SLOTarget: 99, SLOTargets: map[string]float64{"uat": 90}, Window: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	up := map[string]interface{}{"status": aggregator.StatusUp}
+	down := map[string]interface{}{"status": aggregator.StatusDown}
+	h.Observe(monitor.Event{Time: start, Services: map[string]interface{}{"api": up, "uat": up}})
+	h.Observe(monitor.Event{Time: start.Add(90 * time.Minute), Services: map[string]interface{}{"api": down}})
+	h.Observe(monitor.Event{Time: start.Add(96 * time.Minute), Services: map[string]interface{}{"api": up}})
+	// the same status isn't recorded twice
+	h.Observe(monitor.Event{Time: start.Add(97 * time.Minute), Services: map[string]interface{}{"api": up}})
+
+	rp := h.Query("", start.Add(time.Hour), start.Add(2*time.Hour))
+	api := rp.Services["api"]
+	if len(api.Transitions) != 2 {
+		t.Errorf("Query() api transitions = %+v, want 2 within the period", api.Transitions)
+	}
+	if api.Uptime == nil || math.Abs(*api.Uptime-90) > 1e-9 {
+		t.Errorf("Query() api uptime = %v, want 90", api.Uptime)
+	}
+	// 6 minutes of downtime against 36 seconds of budget
+	if api.SLO == nil || math.Abs(api.SLO.Burn-10) > 1e-9 {
+		t.Errorf("Query() api slo = %+v, want burn 10", api.SLO)
+	}
+	if uat := rp.Services["uat"]; uat.SLO == nil || uat.SLO.Target != 90 || uat.SLO.Burn != 0 {
+		t.Errorf("Query() uat slo = %+v", uat.SLO)
+	}
+}
+
+func TestHistory_Query_service(t *testing.T) {
+	h, err := New(&Config{Size: 10, SLOTarget: 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h.Record("api", Transition{Time: start, Status: aggregator.StatusUp})
+	h.Record("uat", Transition{Time: start, Status: aggregator.StatusUp})
+
+	if rp := h.Query("uat", start, start.Add(time.Hour)); len(rp.Services) != 1 || rp.Services["uat"] == nil {
+		t.Errorf("Query(uat) = %+v, want uat only", rp.Services)
+	}
+}
+
+func TestHistory_Query_future(t *testing.T) {
+	h, err := New(&Config{Size: 10, SLOTarget: 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+	h.Record("api", Transition{Time: now.Add(-time.Hour), Status: aggregator.StatusUp})
+	h.Record("api", Transition{Time: now.Add(-30 * time.Minute), Status: aggregator.StatusDown})
+
+	api := h.Query("api", now.Add(-time.Hour), now.Add(time.Hour)).Services["api"]
+	if api.Uptime == nil || math.Abs(*api.Uptime-50) > 1e-9 {
+		t.Errorf("Query() api uptime = %v, want 50 till now", api.Uptime)
+	}
+	if api.SLO == nil || api.SLO.DowntimeSeconds != (30*time.Minute).Seconds() {
+		t.Errorf("Query() api slo = %+v, want 30 minutes of downtime", api.SLO)
+	}
+}
+
+func TestHistory_Persistence(t *testing.T) {
+	cfg := &Config{Size: 2, SLOTarget: 99.9, File: filepath.Join(t.TempDir(), "history.db")}
+	h, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, status := range []string{aggregator.StatusUp, aggregator.StatusDown, aggregator.StatusUp} {
+		h.Record("api", Transition{Time: start.Add(time.Duration(i) * time.Minute), Status: status})
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err = New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ts := h.Query("api", start, start.Add(time.Hour)).Services["api"].Transitions
+	if len(ts) != 2 || ts[0].Status != aggregator.StatusDown || !ts[1].Time.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("loaded transitions = %+v, want the last 2", ts)
+	}
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// historyBucket is a root bucket containing a nested bucket of transitions per service
+var historyBucket = []byte("history")
+
+// store persists transitions in bbolt database keyed by time of the transition
+type store struct {
+	db *bolt.DB
+}
+
+func openStore(file string) (*store, error) {
+	db, err := bolt.Open(file, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open history database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(historyBucket)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to init history database: %w", err)
+	}
+
+	return &store{db: db}, nil
+}
+
+// load reads transitions of all the services ordered by time
+func (s *store) load() (map[string][]Transition, error) {
+	res := map[string][]Transition{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(historyBucket).ForEachBucket(func(service []byte) error {
+			b := tx.Bucket(historyBucket).Bucket(service)
+
+			return b.ForEach(func(_, v []byte) error {
+				var t Transition
+				if err := json.Unmarshal(v, &t); err != nil {
+					return fmt.Errorf("incorrect transition of %s: %w", service, err)
+				}
+				res[string(service)] = append(res[string(service)], t)
+
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load history: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s *store) append(service string, t Transition) error {
+	v, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("unable to marshal transition: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(historyBucket).CreateBucketIfNotExists([]byte(service))
+		if err != nil {
+			return err
+		}
+
+		return b.Put(key(t.Time), v)
+	})
+}
+
+// trim removes the dropped transitions of the service
+func (s *store) trim(service string, dropped []Transition) error {
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(historyBucket).Bucket([]byte(service))
+		if b == nil {
+			return nil
+		}
+		for _, t := range dropped {
+			if err := b.Delete(key(t.Time)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *store) close() error {
+	return s.db.Close()
+}
+
+// key encodes time so that keys are sorted chronologically
+func key(t time.Time) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+
+	return k
+}
@@ -0,0 +1,19 @@
+// Package testutil provides helpers shared by tests
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// WriteSecret writes the value into a file of the test temporary directory the way mounted secrets look like
+func WriteSecret(t testing.TB, name, value string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(value+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
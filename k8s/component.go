@@ -0,0 +1,218 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/probe"
+)
+
+const (
+	componentResync = 10 * time.Minute
+	// componentSyncTimeout limits waiting for the initial list of components, e.g. when the CRD isn't installed
+	componentSyncTimeout = 30 * time.Second
+	// componentStatusRefresh is a period unchanged status of a component is rewritten to refresh lastChecked
+	componentStatusRefresh = time.Minute
+
+	sourceComponent = "ReportPortalComponent/"
+)
+
+// ComponentGVR identifies ReportPortalComponent custom resource
+var ComponentGVR = schema.GroupVersionResource{
+	Group:    "reportportal.io",
+	Version:  "v1alpha1",
+	Resource: "reportportalcomponents",
+}
+
+// Component represents ReportPortalComponent custom resource
+type Component struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ComponentSpec   `json:"spec"`
+	Status ComponentStatus `json:"status,omitempty"`
+}
+
+// ComponentSpec declares how a ReportPortal component is probed
+type ComponentSpec struct {
+	// Name is a component name used in composite responses, defaults to resource name
+	Name string `json:"name,omitempty"`
+	// Service is a name of the k8s Service backing the component
+	Service string `json:"service"`
+	// Port is either a port name or a port number of the Service
+	Port intstr.IntOrString `json:"port,omitempty"`
+	// Scheme of the probes, either http or https
+	Scheme string `json:"scheme,omitempty"`
+	// ProbeType is either http or grpc
+	ProbeType  string   `json:"probeType,omitempty"`
+	InfoPath   string   `json:"infoPath,omitempty"`
+	HealthPath string   `json:"healthPath,omitempty"`
+	Critical   bool     `json:"critical,omitempty"`
+	DependsOn  []string `json:"dependsOn,omitempty"`
+}
+
+// ComponentStatus reflects the latest health check result
+type ComponentStatus struct {
+	Health      string `json:"health,omitempty"`
+	Message     string `json:"message,omitempty"`
+	LastChecked string `json:"lastChecked,omitempty"`
+}
+
+// componentWatcher keeps cached view of ReportPortalComponent resources and updates their status
+type componentWatcher struct {
+	client dynamic.NamespaceableResourceInterface
+	ns     string
+	lister cache.GenericLister
+	now    func() time.Time
+
+	mu sync.Mutex
+	// written holds statuses written last per component
+	written map[string]ComponentStatus
+}
+
+// newComponentWatcher starts an informer watching ReportPortalComponent resources in the namespace.
+// It fails if the informer isn't synced within the timeout, e.g. when the CRD isn't installed or access is denied
+func newComponentWatcher(client dynamic.Interface, ns string, timeout time.Duration) (*componentWatcher, error) {
+	stop := make(chan struct{})
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, componentResync, ns, nil)
+	informer := factory.ForResource(ComponentGVR)
+	factory.Start(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(ctx.Done(), informer.Informer().HasSynced) {
+		close(stop)
+
+		return nil, fmt.Errorf("unable to sync %s informer within %s", ComponentGVR.Resource, timeout)
+	}
+
+	return &componentWatcher{
+		client:  client.Resource(ComponentGVR),
+		ns:      ns,
+		lister:  informer.Lister(),
+		now:     time.Now,
+		written: map[string]ComponentStatus{},
+	}, nil
+}
+
+// nodesInfo converts watched components into nodes info
+func (cw *componentWatcher) nodesInfo(
+	localDomain string,
+	probes *probe.Client,
+	d *aggregator.Discovery,
+) (map[string]*NodeInfo, error) {
+	objs, err := cw.lister.ByNamespace(cw.ns).List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("unable to list components: %w", err)
+	}
+
+	nodesInfo := make(map[string]*NodeInfo, len(objs))
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		var c Component
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &c); err != nil {
+			log.Errorf("Unable to parse component %s: %v", u.GetName(), err)
+			d.Skip(sourceComponent+u.GetName(), fmt.Sprintf("unable to parse: %v", err))
+
+			continue
+		}
+		if c.Spec.Service == "" {
+			log.Warnf("Component %s has no service declared, skipping", c.GetName())
+			d.Skip(sourceComponent+c.GetName(), "service isn't declared")
+
+			continue
+		}
+
+		name := c.Spec.Name
+		if name == "" {
+			name = c.GetName()
+		}
+		ni := &NodeInfo{
+			service:        name,
+			name:           c.Spec.Service,
+			source:         sourceComponent + c.GetName(),
+			srv:            c.Spec.Service + "." + localDomain,
+			scheme:         probes.SchemeFor(name, c.Spec.Scheme),
+			probeType:      probes.TypeFor(name, c.Spec.ProbeType),
+			infoEndpoint:   valueOrDefault(c.Spec.InfoPath, defaultInfoEndpoint),
+			healthEndpoint: valueOrDefault(c.Spec.HealthPath, defaultHealthEndpoint),
+			component:      c.GetName(),
+			critical:       c.Spec.Critical,
+			dependsOn:      c.Spec.DependsOn,
+		}
+		if port := c.Spec.Port.IntValue(); port != 0 {
+			ni.port = port
+		} else {
+			ni.portName = c.Spec.Port.String()
+		}
+		nodesInfo[name] = ni
+	}
+
+	return nodesInfo, nil
+}
+
+// updateStatus records the latest health check result in the component status.
+// The status is written once health or message changes, unchanged one is refreshed at most once per componentStatusRefresh
+func (cw *componentWatcher) updateStatus(ctx context.Context, component, health, message string) error {
+	now := cw.now()
+	cw.mu.Lock()
+	prev, ok := cw.written[component]
+	cw.mu.Unlock()
+	if ok && prev.Health == health && prev.Message == message {
+		if checked, err := time.Parse(time.RFC3339, prev.LastChecked); err == nil && now.Sub(checked) < componentStatusRefresh {
+			return nil
+		}
+	}
+
+	cached, err := cw.lister.ByNamespace(cw.ns).Get(component)
+	if err != nil {
+		return fmt.Errorf("unable to get component %s: %w", component, err)
+	}
+	u, ok := cached.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("unexpected type of component %s: %T", component, cached)
+	}
+
+	cs := ComponentStatus{Health: health, Message: message, LastChecked: now.UTC().Format(time.RFC3339)}
+	status, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&cs)
+	if err != nil {
+		return fmt.Errorf("unable to convert component status: %w", err)
+	}
+	// objects of the informer cache are shared and must not be modified
+	obj := u.DeepCopy()
+	obj.Object["status"] = status
+
+	if _, err := cw.client.Namespace(cw.ns).UpdateStatus(ctx, obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update component %s status: %w", component, err)
+	}
+	cw.mu.Lock()
+	cw.written[component] = cs
+	cw.mu.Unlock()
+
+	return nil
+}
+
+func valueOrDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+
+	return v
+}
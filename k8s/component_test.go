@@ -0,0 +1,174 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/probe"
+)
+
+func newComponent(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": ComponentGVR.GroupVersion().String(),
+		"kind":       "ReportPortalComponent",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "rp"},
+		"spec":       spec,
+	}}
+}
+
+func newComponentClient(objs ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{ComponentGVR: "ReportPortalComponentList"},
+		objs...,
+	)
+}
+
+func Test_componentWatcher_nodesInfo(t *testing.T) {
+	cw, err := newComponentWatcher(newComponentClient(
+		newComponent("api", map[string]interface{}{
+			"service": "reportportal-api", "port": int64(8585), "critical": true, "dependsOn": []interface{}{"postgres"},
+		}),
+		newComponent("analyzer", map[string]interface{}{"name": "analyzer-train", "service": "analyzer", "port": "headless"}),
+		newComponent("broken", map[string]interface{}{"port": "http"}),
+	), "rp", time.Minute)
+	if err != nil {
+		t.Fatalf("newComponentWatcher() error = %v", err)
+	}
+
+	probes, err := probe.NewClient(&probe.Config{
+		Services: map[string]*probe.ServiceConfig{"api": {Scheme: probe.SchemeHTTPS}},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	d := aggregator.NewDiscovery()
+	nodes, err := cw.nodesInfo("rp.svc.cluster.local", probes, d)
+	if err != nil {
+		t.Fatalf("nodesInfo() error = %v", err)
+	}
+	if len(d.Skipped) != 1 || d.Skipped[0].Source != sourceComponent+"broken" {
+		t.Errorf("nodesInfo() skipped = %+v, want broken component", d.Skipped)
+	}
+
+	want := map[string]*NodeInfo{
+		"api": {
+			service: "api", name: "reportportal-api", srv: "reportportal-api.rp.svc.cluster.local",
+			source: sourceComponent + "api", scheme: probe.SchemeHTTPS, probeType: probe.TypeHTTP, port: 8585,
+			infoEndpoint: defaultInfoEndpoint, healthEndpoint: defaultHealthEndpoint,
+			component: "api", critical: true, dependsOn: []string{"postgres"},
+		},
+		"analyzer-train": {
+			service: "analyzer-train", name: "analyzer", srv: "analyzer.rp.svc.cluster.local",
+			source: sourceComponent + "analyzer", scheme: probe.SchemeHTTP, probeType: probe.TypeHTTP, portName: "headless",
+			infoEndpoint: defaultInfoEndpoint, healthEndpoint: defaultHealthEndpoint, component: "analyzer",
+		},
+	}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Fatalf("nodesInfo() = %+v, want %+v", nodes, want)
+	}
+	if got := nodes["api"].endpoint("/info"); got != "https://reportportal-api.rp.svc.cluster.local:8585/info" {
+		t.Errorf("endpoint() = %v", got)
+	}
+}
+
+func Test_componentWatcher_updateStatus(t *testing.T) {
+	client := newComponentClient(newComponent("api", map[string]interface{}{"service": "reportportal-api"}))
+	cw, err := newComponentWatcher(client, "rp", time.Minute)
+	if err != nil {
+		t.Fatalf("newComponentWatcher() error = %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cw.now = func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		after   time.Duration
+		health  string
+		message string
+		writes  int
+	}{
+		{name: "first result", health: aggregator.StatusUp, writes: 1},
+		{name: "unchanged", after: time.Second, health: aggregator.StatusUp, writes: 1},
+		{name: "health changed", after: time.Second, health: aggregator.StatusDown, message: "refused", writes: 2},
+		{name: "message changed", after: time.Second, health: aggregator.StatusDown, message: "timeout", writes: 3},
+		{name: "refreshed", after: componentStatusRefresh, health: aggregator.StatusDown, message: "timeout", writes: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.after)
+			if err := cw.updateStatus(context.Background(), "api", tt.health, tt.message); err != nil {
+				t.Fatalf("updateStatus() error = %v", err)
+			}
+			if got := countActions(client, "update"); got != tt.writes {
+				t.Errorf("updateStatus() writes = %d, want %d", got, tt.writes)
+			}
+		})
+	}
+}
+
+func countActions(client *dynamicfake.FakeDynamicClient, verb string) int {
+	n := 0
+	for _, a := range client.Actions() {
+		if a.GetVerb() == verb {
+			n++
+		}
+	}
+
+	return n
+}
+
+func Test_newComponentWatcher_timeout(t *testing.T) {
+	client := newComponentClient()
+	client.PrependReactor("list", ComponentGVR.Resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(ComponentGVR.GroupResource(), "", nil)
+	})
+
+	if _, err := newComponentWatcher(client, "rp", 100*time.Millisecond); err == nil {
+		t.Error("newComponentWatcher() error = nil, want sync timeout")
+	}
+}
+
+func TestNodeInfo_withTopology(t *testing.T) {
+	tests := []struct {
+		name string
+		ni   *NodeInfo
+		rs   map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "plain",
+			ni:   &NodeInfo{},
+			rs:   map[string]interface{}{"status": aggregator.StatusUp},
+			want: map[string]interface{}{"status": aggregator.StatusUp},
+		},
+		{
+			name: "critical with dependencies",
+			ni:   &NodeInfo{critical: true, dependsOn: []string{"postgres"}},
+			rs:   map[string]interface{}{"status": aggregator.StatusDown},
+			want: map[string]interface{}{"status": aggregator.StatusDown, "critical": true, "dependsOn": []string{"postgres"}},
+		},
+		{
+			name: "empty result",
+			ni:   &NodeInfo{critical: true},
+			want: map[string]interface{}{"critical": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ni.withTopology(tt.rs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withTopology() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
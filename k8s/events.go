@@ -0,0 +1,132 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+const (
+	eventSourceComponent = "service-index"
+
+	reasonComponentDown    = "ComponentDown"
+	reasonComponentUp      = "ComponentUp"
+	reasonComponentChanged = "ComponentHealthChanged"
+)
+
+// healthEvents records Kubernetes Events when health status of a service changes
+type healthEvents struct {
+	recorder record.EventRecorder
+	ns       string
+
+	mu   sync.Mutex
+	last map[string]string
+}
+
+// newEventRecorder creates recorder publishing events to the namespace
+func newEventRecorder(clientset kubernetes.Interface, ns string) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events(ns)})
+
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: eventSourceComponent})
+}
+
+func newHealthEvents(recorder record.EventRecorder, ns string) *healthEvents {
+	return &healthEvents{
+		recorder: recorder,
+		ns:       ns,
+		last:     map[string]string{},
+	}
+}
+
+// observe remembers the latest status of the service and emits an event against its Service if it differs from the previous one
+func (he *healthEvents) observe(service string, ni *NodeInfo, status, errMsg string) {
+	he.mu.Lock()
+	prev, known := he.last[service]
+	he.last[service] = status
+	he.mu.Unlock()
+
+	if !known || prev == status {
+		return
+	}
+
+	eventType := corev1.EventTypeNormal
+	reason := reasonComponentChanged
+	switch status {
+	case aggregator.StatusDown:
+		eventType = corev1.EventTypeWarning
+		reason = reasonComponentDown
+	case aggregator.StatusUp:
+		reason = reasonComponentUp
+	}
+
+	msg := fmt.Sprintf("Health of %s changed from %s to %s, endpoint %s%s", service, prev, status, ni.srv, ni.healthEndpoint)
+	if errMsg != "" {
+		msg += ": " + errMsg
+	}
+
+	ref := &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Service",
+		Namespace:  he.ns,
+		Name:       ni.name,
+	}
+	he.recorder.Event(ref, eventType, reason, msg)
+}
+
+// eventsAggregator records Kubernetes Events on changes of health reported by the delegate
+type eventsAggregator struct {
+	delegate aggregator.Aggregator
+	k8s      *Aggregator
+}
+
+// WithEvents wraps the aggregator so that health transitions of k8s nodes are recorded as Kubernetes Events.
+// The delegate is expected to smooth statuses so that flapping probes don't flood the cluster with events.
+// It returns the delegate as is if recording of events is disabled
+func (a *Aggregator) WithEvents(delegate aggregator.Aggregator) aggregator.Aggregator {
+	if a.events == nil {
+		return delegate
+	}
+
+	return &eventsAggregator{delegate: delegate, k8s: a}
+}
+
+// AggregateInfo collects information from info endpoints
+func (ea *eventsAggregator) AggregateInfo(ctx context.Context) map[string]interface{} {
+	return ea.delegate.AggregateInfo(ctx)
+}
+
+// AggregateHealth aggregates information from health endpoints recording events of changed statuses
+func (ea *eventsAggregator) AggregateHealth(ctx context.Context) map[string]interface{} {
+	health := ea.delegate.AggregateHealth(ctx)
+	for name, entry := range health {
+		// entries of other sources, e.g. dependencies, have no k8s node
+		ni := ea.k8s.node(name)
+		if ni == nil {
+			continue
+		}
+		e, _ := entry.(map[string]interface{})
+		errMsg, _ := e["error"].(string)
+		ea.k8s.events.observe(name, ni, statusOf(e), errMsg)
+	}
+
+	return health
+}
+
+// Services returns names of discovered services
+func (ea *eventsAggregator) Services(ctx context.Context) ([]string, error) {
+	return ea.delegate.Services(ctx)
+}
+
+// Discover resolves nodes and describes the result along with the ignored objects
+func (ea *eventsAggregator) Discover(ctx context.Context) *aggregator.Discovery {
+	return ea.delegate.Discover(ctx)
+}
@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/aggregator/aggregatortest"
+)
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+
+	return events
+}
+
+func Test_healthEvents_observe(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	he := newHealthEvents(recorder, "rp")
+	ni := &NodeInfo{name: "reportportal-api", srv: "reportportal-api.rp.svc.cluster.local", healthEndpoint: "/health"}
+
+	he.observe("api", ni, aggregator.StatusUp, "")
+	he.observe("api", ni, aggregator.StatusUp, "")
+	he.observe("api", ni, aggregator.StatusDown, "connection refused")
+	// another component backed by the same Service has state of its own
+	he.observe("api-v2", ni, aggregator.StatusUp, "")
+	he.observe("api", ni, aggregator.StatusUp, "")
+
+	events := drainEvents(recorder)
+	if len(events) != 2 {
+		t.Fatalf("observe() recorded %d events, want 2: %v", len(events), events)
+	}
+	if !strings.HasPrefix(events[0], "Warning "+reasonComponentDown) || !strings.Contains(events[0], "connection refused") {
+		t.Errorf("observe() first event = %v", events[0])
+	}
+	if !strings.HasPrefix(events[1], "Normal "+reasonComponentUp) || !strings.Contains(events[1], "Health of api ") {
+		t.Errorf("observe() second event = %v", events[1])
+	}
+}
+
+func TestAggregator_WithEvents(t *testing.T) {
+	up := map[string]interface{}{"status": aggregator.StatusUp}
+	down := map[string]interface{}{"status": aggregator.StatusDown, "error": "connection refused"}
+	delegate := &aggregatortest.Stub{Script: []map[string]interface{}{
+		{"api": up, "postgres": up},
+		{"api": down, "postgres": down},
+		{"api": down, "postgres": up},
+	}}
+
+	if got := (&Aggregator{}).WithEvents(delegate); got != delegate {
+		t.Errorf("WithEvents() = %v, want delegate as events are disabled", got)
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	a := &Aggregator{
+		events: newHealthEvents(recorder, "rp"),
+		nodes:  map[string]*NodeInfo{"api": {service: "api", name: "reportportal-api", srv: "reportportal-api.rp", healthEndpoint: "/health"}},
+	}
+	ea := a.WithEvents(delegate)
+	for checks := len(delegate.Script); checks > 0; checks-- {
+		ea.AggregateHealth(context.Background())
+	}
+
+	events := drainEvents(recorder)
+	if len(events) != 1 || !strings.HasPrefix(events[0], "Warning "+reasonComponentDown) ||
+		!strings.Contains(events[0], "connection refused") {
+		t.Errorf("AggregateHealth() recorded events %v, want single api failure", events)
+	}
+}
@@ -5,18 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"net"
-	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/go-resty/resty/v2"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // all auth types are supported
 	"k8s.io/client-go/rest"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/probe"
 )
 
 const (
@@ -24,28 +29,66 @@ const (
 	//nolint:gosec
 	nsSecret      = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
 	labelSelector = "app=reportportal"
+
+	sourceService = "Service/"
+
+	defaultInfoEndpoint   = "/info"
+	defaultHealthEndpoint = "/health"
 )
 
-var errEmptyResponse = errors.New("response is empty")
+var (
+	errEmptyResponse = errors.New("response is empty")
+	errNoGRPCInfo    = errors.New("info isn't available for gRPC probes")
+	errNoSRVRecords  = errors.New("no SRV records found")
+)
 
 // Aggregator is an info/health aggregator implementation for k8s
 type Aggregator struct {
 	localDomain string
 	ns          string
-	clientset   *kubernetes.Clientset
-	r           *resty.Client
+	clientset   kubernetes.Interface
+	// r contains REST clients per probe scheme
+	r      map[string]*resty.Client
+	probes *probe.Client
+	// workloadInfo enables enrichment of info entries with workload metadata
+	workloadInfo bool
+	// components watches ReportPortalComponent resources, nil if disabled
+	components *componentWatcher
+	// events records health transitions as Kubernetes Events, nil if disabled
+	events *healthEvents
+
+	mu sync.Mutex
+	// nodes are the latest nodes probed by the aggregator
+	nodes map[string]*NodeInfo
 }
 
 // NodeInfo embeds node-related information
 type NodeInfo struct {
-	srv            string
+	// service is a name of the node in composite responses
+	service  string
+	name     string
+	selector map[string]string
+	srv      string
+	// source is a Kubernetes object the node is discovered from
+	source         string
+	scheme         string
+	probeType      string
 	portName       string
+	port           int
 	infoEndpoint   string
 	healthEndpoint string
+	// component is a name of ReportPortalComponent resource declaring the node
+	component string
+	// critical nodes bring overall status down once they fail
+	critical  bool
+	dependsOn []string
 }
 
 // NewAggregator creates new k8s aggregator
-func NewAggregator(timeout time.Duration) (*Aggregator, error) {
+func NewAggregator(
+	probes *probe.Client,
+	workloadInfo, componentsCRD, recordEvents bool,
+) (*Aggregator, error) {
 	ns, err := getCurrentNamespace()
 	if err != nil {
 		return nil, fmt.Errorf("unable to find out current namespace: %w", err)
@@ -64,86 +107,237 @@ func NewAggregator(timeout time.Duration) (*Aggregator, error) {
 		return nil, fmt.Errorf("unable to create k8s client: %w", err)
 	}
 
+	var components *componentWatcher
+	if componentsCRD {
+		dynClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create k8s dynamic client: %w", err)
+		}
+		components, err = newComponentWatcher(dynClient, ns, componentSyncTimeout)
+		if err != nil {
+			log.Warnf("Unable to watch components, falling back to annotated services: %v", err)
+		}
+	}
+
+	var events *healthEvents
+	if recordEvents {
+		events = newHealthEvents(newEventRecorder(clientset, ns), ns)
+	}
+
 	clusterDomain := getClusterDomain()
 
 	return &Aggregator{
 		clientset:   clientset,
 		localDomain: fmt.Sprintf(domainPattern, ns, clusterDomain),
-		r: resty.NewWithClient(&http.Client{
-			Timeout: timeout,
-		}).SetScheme("http"),
-		ns: ns,
+		r: map[string]*resty.Client{
+			probe.SchemeHTTP:  probes.NewRestClient().SetScheme(probe.SchemeHTTP),
+			probe.SchemeHTTPS: probes.NewRestClient().SetScheme(probe.SchemeHTTPS),
+		},
+		probes:       probes,
+		ns:           ns,
+		workloadInfo: workloadInfo,
+		components:   components,
+		events:       events,
 	}, nil
 }
 
 // AggregateHealth aggregates health info
-func (a *Aggregator) AggregateHealth() map[string]interface{} {
-	return a.aggregate(func(ni *NodeInfo) (interface{}, error) {
+func (a *Aggregator) AggregateHealth(ctx context.Context) map[string]interface{} {
+	health, unfinished := a.aggregate(ctx, func(ctx context.Context, ni *NodeInfo) (interface{}, error) {
+		ctx, cancel := a.probes.HealthContext(ctx, ni.service)
+		defer cancel()
+
 		var rs map[string]interface{}
-		_, e := a.r.R().SetSRV(&resty.SRVRecord{Service: ni.portName, Domain: ni.srv}).SetResult(&rs).SetError(&rs).Get(ni.healthEndpoint)
+		var msg string
+		e := a.probes.Do(ni.service, func() error {
+			var err error
+			rs, err = a.checkHealth(ctx, ni)
+
+			return err
+		})
 		if nil != e {
 			log.Errorf("Health check error for service [%s] failed: %s", ni.srv, e.Error())
-			rs = map[string]interface{}{"status": "DOWN"}
+			msg = e.Error()
+		}
+		rs = ni.withTopology(a.probes.HealthResult(ni.service, rs, e))
+
+		if a.components != nil && ni.component != "" {
+			if err := a.components.updateStatus(context.Background(), ni.component, statusOf(rs), msg); nil != err {
+				log.Warnf("Unable to update component status: %v", err)
+			}
 		}
 
 		return rs, nil
 	})
+
+	return aggregator.MarkTimeouts(health, unfinished)
 }
 
 // AggregateInfo aggregates info
-func (a *Aggregator) AggregateInfo() map[string]interface{} {
-	return a.aggregate(func(ni *NodeInfo) (interface{}, error) {
+func (a *Aggregator) AggregateInfo(ctx context.Context) map[string]interface{} {
+	info, _ := a.aggregate(ctx, func(ctx context.Context, ni *NodeInfo) (interface{}, error) {
+		if ni.probeType == probe.TypeGRPC {
+			return nil, errNoGRPCInfo
+		}
+		ctx, cancel := a.probes.InfoContext(ctx, ni.service)
+		defer cancel()
+
 		var rs map[string]interface{}
-		_, e := a.r.R().SetSRV(&resty.SRVRecord{Service: ni.portName, Domain: ni.srv}).SetResult(&rs).Get(ni.infoEndpoint)
+		var resp *resty.Response
+		rq, e := a.newRequest(ctx, ni)
+		if nil == e {
+			e = a.probes.Do(ni.service, func() error {
+				var err error
+				resp, err = rq.SetResult(&rs).Get(ni.endpoint(ni.infoEndpoint))
+
+				return err
+			})
+		}
 		if nil != e {
 			log.Errorf("Unable to aggregate info: %v", e)
 
 			return nil, fmt.Errorf("unable to aggregate info: %w", e)
 		}
+		rs = a.probes.ValidateInfo(ni.service, resp, rs)
 		if nil == rs {
 			log.Errorf("Unable to collect info endpoint response from service %s:%s, endpoint %s", ni.srv, ni.portName, ni.infoEndpoint)
 
 			return nil, errEmptyResponse
 		}
 
+		if a.workloadInfo {
+			wi, err := a.getWorkloadInfo(ctx, ni)
+			if nil != err {
+				log.Warnf("Unable to collect workload info for service %s: %v", ni.name, err)
+			} else {
+				rs["workload"] = wi
+			}
+		}
+
 		return rs, nil
 	})
+
+	return info
 }
 
-func (a *Aggregator) aggregate(f func(ni *NodeInfo) (interface{}, error)) map[string]interface{} {
+// Services returns names of discovered services
+func (a *Aggregator) Services(ctx context.Context) ([]string, error) {
+	nodesInfo, err := a.getNodesInfo(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregator.Names(nodesInfo), nil
+}
+
+// Discover resolves nodes and describes the result along with the ignored objects
+func (a *Aggregator) Discover(ctx context.Context) *aggregator.Discovery {
+	d := aggregator.NewDiscovery()
+	nodesInfo, err := a.getNodesInfo(ctx, d)
+	if err != nil {
+		d.Fail(err)
+
+		return d
+	}
+	for _, ni := range nodesInfo {
+		d.Add(aggregator.DiscoveredNode{
+			Service:        ni.service,
+			Source:         ni.source,
+			Target:         ni.target(),
+			ProbeType:      ni.probeType,
+			InfoEndpoint:   ni.infoEndpoint,
+			HealthEndpoint: ni.healthEndpoint,
+			Critical:       ni.critical,
+			DependsOn:      ni.dependsOn,
+		})
+	}
+
+	return d
+}
+
+// checkHealth probes health of the node according to its probe type
+func (a *Aggregator) checkHealth(ctx context.Context, ni *NodeInfo) (map[string]interface{}, error) {
+	if ni.probeType == probe.TypeGRPC {
+		target, err := ni.grpcTarget(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return a.probes.GRPCHealth(ctx, ni.service, target, ni.scheme == probe.SchemeHTTPS)
+	}
+
+	rq, err := a.newRequest(ctx, ni)
+	if err != nil {
+		return nil, err
+	}
+	var rs map[string]interface{}
+	resp, err := rq.SetResult(&rs).SetError(&rs).Get(ni.endpoint(ni.healthEndpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.probes.ValidateHealth(ni.service, resp, rs), nil
+}
+
+func (a *Aggregator) aggregate(
+	ctx context.Context,
+	f func(ctx context.Context, ni *NodeInfo) (interface{}, error),
+) (map[string]interface{}, []string) {
+	ctx, cancel := a.probes.AggregationContext(ctx)
+	defer cancel()
+
 	log.Debug("Aggregating node information")
-	nodesInfo, err := a.getNodesInfo()
+	nodesInfo, err := a.getNodesInfo(ctx, nil)
 	if err != nil {
 		log.Errorf("Unable to aggregate node information: %v", err)
 
-		return map[string]interface{}{}
+		return map[string]interface{}{}, nil
 	}
+	a.mu.Lock()
+	a.nodes = nodesInfo
+	a.mu.Unlock()
 
-	nodeLen := len(nodesInfo)
-	aggregated := make(map[string]interface{}, nodeLen)
-	var wg sync.WaitGroup
+	return aggregator.Fanout(ctx, nodesInfo, a.probes.Concurrency(), f)
+}
+
+// getNodesInfo resolves nodes from annotated services and components, ignored objects are recorded to the discovery
+func (a *Aggregator) getNodesInfo(ctx context.Context, d *aggregator.Discovery) (map[string]*NodeInfo, error) {
+	ctx, cancel := a.probes.DiscoveryContext(ctx)
+	defer cancel()
+
+	nodesInfo, err := a.servicesInfo(ctx, d)
+	if err != nil {
+		return nil, err
+	}
 
-	wg.Add(nodeLen)
-	var mu sync.Mutex
-	for node, info := range nodesInfo {
-		go func(n string, ni *NodeInfo) {
-			defer wg.Done()
-			res, err := f(ni)
-			if nil == err {
-				mu.Lock()
-				aggregated[n] = res
-				mu.Unlock()
+	if a.components != nil {
+		components, err := a.components.nodesInfo(a.localDomain, a.probes, d)
+		if err != nil {
+			return nil, err
+		}
+		// components declared explicitly take precedence over annotated services
+		for name, ni := range components {
+			if prev, ok := nodesInfo[name]; ok {
+				d.Skip(prev.source, "overridden by "+ni.source)
 			}
-		}(node, info)
+			nodesInfo[name] = ni
+		}
+	}
+
+	for name, ni := range nodesInfo {
+		if err := a.probes.CheckTarget(ni.scheme+"://"+ni.srv, ni.infoEndpoint, ni.healthEndpoint); err != nil {
+			d.Skip(ni.source, err.Error())
+			delete(nodesInfo, name)
+		}
 	}
-	wg.Wait()
 
-	return aggregated
+	return nodesInfo, nil
 }
 
-func (a *Aggregator) getNodesInfo() (map[string]*NodeInfo, error) {
+// servicesInfo resolves nodes from ReportPortal's services annotated with name of the service
+func (a *Aggregator) servicesInfo(ctx context.Context, d *aggregator.Discovery) (map[string]*NodeInfo, error) {
 	services, err := a.clientset.CoreV1().Services(a.ns).List(
-		context.Background(),
+		ctx,
 		metav1.ListOptions{
 			LabelSelector: labelSelector,
 		})
@@ -154,34 +348,139 @@ func (a *Aggregator) getNodesInfo() (map[string]*NodeInfo, error) {
 	srvCount := len(services.Items)
 	log.Infof("Selected [%d] ReportPortal's services", srvCount)
 	nodesInfo := make(map[string]*NodeInfo, srvCount)
-	for _, srv := range services.Items {
+	for i := range services.Items {
+		srv := &services.Items[i]
 		log.Debugf("Info found for service %s", srv.GetName())
 
 		srvName := srv.GetAnnotations()["service"]
 		if srvName == "" {
+			d.Skip(sourceService+srv.GetName(), "service annotation is missing")
+
 			continue
 		}
+		nodesInfo[srvName] = a.serviceNodeInfo(srvName, srv)
+	}
 
-		ni := &NodeInfo{srv: srv.GetName() + "." + a.localDomain}
-		if ie, ok := srv.GetAnnotations()["infoEndpoint"]; ok {
-			ni.infoEndpoint = ie
-		} else {
-			ni.infoEndpoint = "/info"
-		}
-		if he, ok := srv.GetAnnotations()["healthEndpoint"]; ok {
-			ni.healthEndpoint = he
-		} else {
-			ni.healthEndpoint = "/health"
-		}
+	return nodesInfo, nil
+}
 
-		if len(srv.Spec.Ports) > 0 {
-			ni.portName = srv.Spec.Ports[0].Name
-		}
+// serviceNodeInfo describes node of the annotated service
+func (a *Aggregator) serviceNodeInfo(srvName string, srv *corev1.Service) *NodeInfo {
+	annotations := srv.GetAnnotations()
+	ni := &NodeInfo{
+		service:        srvName,
+		name:           srv.GetName(),
+		source:         sourceService + srv.GetName(),
+		selector:       srv.Spec.Selector,
+		srv:            srv.GetName() + "." + a.localDomain,
+		scheme:         a.probes.SchemeFor(srvName, annotations["scheme"]),
+		probeType:      a.probes.TypeFor(srvName, annotations["probeType"]),
+		infoEndpoint:   annotationOr(annotations, "infoEndpoint", defaultInfoEndpoint),
+		healthEndpoint: annotationOr(annotations, "healthEndpoint", defaultHealthEndpoint),
+	}
+	if len(srv.Spec.Ports) > 0 {
+		ni.portName = srv.Spec.Ports[0].Name
+	}
 
-		nodesInfo[srvName] = ni
+	return ni
+}
+
+// annotationOr returns value of the annotation, or the default one if the annotation isn't declared
+func annotationOr(annotations map[string]string, key, def string) string {
+	if v, ok := annotations[key]; ok {
+		return v
 	}
 
-	return nodesInfo, nil
+	return def
+}
+
+// newRequest creates an authenticated request to the node resolved either via SRV record or explicit port
+func (a *Aggregator) newRequest(ctx context.Context, ni *NodeInfo) (*resty.Request, error) {
+	r, ok := a.r[ni.scheme]
+	if !ok {
+		r = a.r[probe.SchemeHTTP]
+	}
+	rq := r.R().SetContext(ctx)
+	if ni.port == 0 {
+		rq.SetSRV(&resty.SRVRecord{Service: ni.portName, Domain: ni.srv})
+	}
+	if err := a.probes.Authenticate(ni.service, rq); err != nil {
+		return nil, err
+	}
+
+	return rq, nil
+}
+
+// grpcTarget resolves address of the node, SRV record is looked up if port isn't declared explicitly
+func (ni *NodeInfo) grpcTarget(ctx context.Context) (string, error) {
+	if ni.port != 0 {
+		return net.JoinHostPort(ni.srv, strconv.Itoa(ni.port)), nil
+	}
+
+	_, addrs, err := net.DefaultResolver.LookupSRV(ctx, ni.portName, "tcp", ni.srv)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve SRV record: %w", err)
+	}
+	if len(addrs) == 0 {
+		return "", errNoSRVRecords
+	}
+
+	return net.JoinHostPort(strings.TrimSuffix(addrs[0].Target, "."), strconv.Itoa(int(addrs[0].Port))), nil
+}
+
+// target describes address of the node, either URL or SRV record
+func (ni *NodeInfo) target() string {
+	if ni.port == 0 {
+		return fmt.Sprintf("SRV _%s._tcp.%s", ni.portName, ni.srv)
+	}
+
+	return fmt.Sprintf("%s://%s:%d", ni.scheme, ni.srv, ni.port)
+}
+
+// withTopology marks health entry of the node declared critical or depending on other nodes
+func (ni *NodeInfo) withTopology(rs map[string]interface{}) map[string]interface{} {
+	if !ni.critical && len(ni.dependsOn) == 0 {
+		return rs
+	}
+	if rs == nil {
+		rs = map[string]interface{}{}
+	}
+	if ni.critical {
+		rs[aggregator.KeyCritical] = true
+	}
+	if len(ni.dependsOn) > 0 {
+		rs["dependsOn"] = ni.dependsOn
+	}
+
+	return rs
+}
+
+// endpoint returns request URL of the node's endpoint
+func (ni *NodeInfo) endpoint(path string) string {
+	if ni.port == 0 {
+		return path
+	}
+
+	base := url.URL{Scheme: ni.scheme, Host: net.JoinHostPort(ni.srv, strconv.Itoa(ni.port))}
+
+	return base.JoinPath(path).String()
+}
+
+// node returns the latest probed node of the service, nil if there is none
+func (a *Aggregator) node(service string) *NodeInfo {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.nodes[service]
+}
+
+// statusOf extracts status from the health response
+func statusOf(rs map[string]interface{}) string {
+	if s, ok := rs["status"].(string); ok {
+		return s
+	}
+
+	return aggregator.StatusUnknown
 }
 
 func getCurrentNamespace() (string, error) {
@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/probe"
+)
+
+func TestAggregator_Discover(t *testing.T) {
+	labels := map[string]string{"app": "reportportal"}
+	cs := fake.NewSimpleClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "reportportal-api", Namespace: "rp", Labels: labels,
+				Annotations: map[string]string{"service": "api", "infoEndpoint": "/api/info"},
+			},
+			Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "headless"}}},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "reportportal-postgres", Namespace: "rp", Labels: labels},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "reportportal-uat", Namespace: "rp", Labels: labels,
+				Annotations: map[string]string{"service": "uat", "infoEndpoint": "http://169.254.169.254/latest/meta-data"},
+			},
+		},
+	)
+	probes, err := probe.NewClient(&probe.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Aggregator{localDomain: "rp.svc.cluster.local", ns: "rp", clientset: cs, probes: probes}
+	d := a.Discover(context.Background())
+	if d.Error != "" {
+		t.Fatalf("Discover() error = %v", d.Error)
+	}
+	want := []aggregator.DiscoveredNode{{
+		Service:        "api",
+		Source:         "Service/reportportal-api",
+		Target:         "SRV _headless._tcp.reportportal-api.rp.svc.cluster.local",
+		ProbeType:      probe.TypeHTTP,
+		InfoEndpoint:   "/api/info",
+		HealthEndpoint: defaultHealthEndpoint,
+	}}
+	if !reflect.DeepEqual(d.Nodes, want) {
+		t.Errorf("Discover() nodes = %+v, want %+v", d.Nodes, want)
+	}
+	wantSkipped := []string{"Service/reportportal-postgres", "Service/reportportal-uat"}
+	if got := skippedSources(d); !reflect.DeepEqual(got, wantSkipped) || !strings.Contains(d.Skipped[1].Reason, "another host") {
+		t.Errorf("Discover() skipped = %+v, want %v", d.Skipped, wantSkipped)
+	}
+}
+
+func skippedSources(d *aggregator.Discovery) []string {
+	sources := make([]string, 0, len(d.Skipped))
+	for _, s := range d.Skipped {
+		sources = append(sources, s.Source)
+	}
+
+	return sources
+}
+
+func TestNodeInfo_endpoint(t *testing.T) {
+	byPort := &NodeInfo{scheme: probe.SchemeHTTP, srv: "api", port: 8585}
+	tests := []struct {
+		name string
+		ni   *NodeInfo
+		path string
+		want string
+	}{
+		{name: "port", ni: byPort, path: "/health", want: "http://api:8585/health"},
+		{name: "userinfo path", ni: byPort, path: "@example.com/x", want: "http://api:8585/@example.com/x"},
+		{name: "SRV record", ni: &NodeInfo{scheme: probe.SchemeHTTP, srv: "api", portName: "http"}, path: "/health", want: "/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ni.endpoint(tt.path); got != tt.want {
+				t.Errorf("endpoint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	kindDeployment  = "Deployment"
+	kindStatefulSet = "StatefulSet"
+	kindReplicaSet  = "ReplicaSet"
+)
+
+var (
+	errNoSelector = errors.New("service has no pod selector")
+	errNoPods     = errors.New("no pods match service selector")
+	errNoOwner    = errors.New("pod is not managed by a Deployment or StatefulSet")
+)
+
+// WorkloadInfo describes the workload backing a service
+type WorkloadInfo struct {
+	Kind          string           `json:"kind"`
+	Name          string           `json:"name"`
+	Replicas      int32            `json:"replicas"`
+	ReadyReplicas int32            `json:"readyReplicas"`
+	Containers    []ContainerImage `json:"containers,omitempty"`
+	Pods          []PodInfo        `json:"pods,omitempty"`
+}
+
+// ContainerImage represents container image declared by the workload
+type ContainerImage struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	Tag   string `json:"tag,omitempty"`
+}
+
+// PodInfo represents restart statistics of a single replica
+type PodInfo struct {
+	Name              string     `json:"name"`
+	Ready             bool       `json:"ready"`
+	Restarts          int32      `json:"restarts"`
+	LastRestartReason string     `json:"lastRestartReason,omitempty"`
+	LastRestartTime   *time.Time `json:"lastRestartTime,omitempty"`
+}
+
+// getWorkloadInfo resolves Deployment or StatefulSet backing the service along with its pods
+func (a *Aggregator) getWorkloadInfo(ctx context.Context, ni *NodeInfo) (*WorkloadInfo, error) {
+	if len(ni.selector) == 0 {
+		return nil, errNoSelector
+	}
+
+	pods, err := a.clientset.CoreV1().Pods(a.ns).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(ni.selector).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, errNoPods
+	}
+
+	wi, err := a.getWorkload(ctx, &pods.Items[0])
+	if err != nil {
+		return nil, err
+	}
+
+	wi.Pods = make([]PodInfo, 0, len(pods.Items))
+	for i := range pods.Items {
+		wi.Pods = append(wi.Pods, toPodInfo(&pods.Items[i]))
+	}
+
+	return wi, nil
+}
+
+// getWorkload follows pod's controller references up to the Deployment or StatefulSet
+func (a *Aggregator) getWorkload(ctx context.Context, pod *corev1.Pod) (*WorkloadInfo, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return nil, errNoOwner
+	}
+
+	apps := a.clientset.AppsV1()
+	switch owner.Kind {
+	case kindStatefulSet:
+		sts, err := apps.StatefulSets(a.ns).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get statefulset: %w", err)
+		}
+
+		return &WorkloadInfo{
+			Kind:          kindStatefulSet,
+			Name:          sts.GetName(),
+			Replicas:      replicasOrDefault(sts.Spec.Replicas),
+			ReadyReplicas: sts.Status.ReadyReplicas,
+			Containers:    toContainerImages(sts.Spec.Template.Spec.Containers),
+		}, nil
+	case kindReplicaSet:
+		rs, err := apps.ReplicaSets(a.ns).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get replicaset: %w", err)
+		}
+		rsOwner := metav1.GetControllerOf(rs)
+		if rsOwner == nil || rsOwner.Kind != kindDeployment {
+			return nil, errNoOwner
+		}
+		dpl, err := apps.Deployments(a.ns).Get(ctx, rsOwner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to get deployment: %w", err)
+		}
+
+		return &WorkloadInfo{
+			Kind:          kindDeployment,
+			Name:          dpl.GetName(),
+			Replicas:      replicasOrDefault(dpl.Spec.Replicas),
+			ReadyReplicas: dpl.Status.ReadyReplicas,
+			Containers:    toContainerImages(dpl.Spec.Template.Spec.Containers),
+		}, nil
+	default:
+		return nil, errNoOwner
+	}
+}
+
+func toPodInfo(pod *corev1.Pod) PodInfo {
+	pi := PodInfo{Name: pod.GetName()}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			pi.Ready = c.Status == corev1.ConditionTrue
+		}
+	}
+
+	for i := range pod.Status.ContainerStatuses {
+		cs := &pod.Status.ContainerStatuses[i]
+		pi.Restarts += cs.RestartCount
+
+		term := cs.LastTerminationState.Terminated
+		if term == nil {
+			continue
+		}
+		finished := term.FinishedAt.Time
+		if pi.LastRestartTime == nil || finished.After(*pi.LastRestartTime) {
+			pi.LastRestartTime = &finished
+			pi.LastRestartReason = term.Reason
+		}
+	}
+
+	return pi
+}
+
+func toContainerImages(containers []corev1.Container) []ContainerImage {
+	images := make([]ContainerImage, 0, len(containers))
+	for _, c := range containers {
+		images = append(images, ContainerImage{
+			Name:  c.Name,
+			Image: c.Image,
+			Tag:   imageTag(c.Image),
+		})
+	}
+
+	return images
+}
+
+// imageTag extracts tag from the image reference, e.g. reportportal/service-api:5.11.0 -> 5.11.0
+func imageTag(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || i < strings.LastIndex(image, "/") {
+		return "latest"
+	}
+
+	return image[i+1:]
+}
+
+// replicasOrDefault returns desired replicas count, Kubernetes defaults it to 1
+func replicasOrDefault(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+
+	return *r
+}
@@ -0,0 +1,87 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_getWorkloadInfo(t *testing.T) {
+	isController := true
+	replicas := int32(2)
+	finished := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	selector := map[string]string{"component": "api"}
+
+	cs := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "rp"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+					{Name: "api", Image: "reportportal/service-api:5.11.0"},
+				}}},
+			},
+			Status: appsv1.DeploymentStatus{ReadyReplicas: 1},
+		},
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "api-5d8f", Namespace: "rp",
+				OwnerReferences: []metav1.OwnerReference{{Kind: kindDeployment, Name: "api", Controller: &isController}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "api-5d8f-abc", Namespace: "rp", Labels: selector,
+				OwnerReferences: []metav1.OwnerReference{{Kind: kindReplicaSet, Name: "api-5d8f", Controller: &isController}},
+			},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				RestartCount: 3,
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Reason:     "OOMKilled",
+					FinishedAt: metav1.NewTime(finished),
+				}},
+			}}},
+		},
+	)
+
+	a := &Aggregator{ns: "rp", clientset: cs}
+	wi, err := a.getWorkloadInfo(context.Background(), &NodeInfo{name: "api", selector: selector})
+	if err != nil {
+		t.Fatalf("getWorkloadInfo() error = %v", err)
+	}
+	want := &WorkloadInfo{
+		Kind:          kindDeployment,
+		Name:          "api",
+		Replicas:      2,
+		ReadyReplicas: 1,
+		Containers:    []ContainerImage{{Name: "api", Image: "reportportal/service-api:5.11.0", Tag: "5.11.0"}},
+		Pods:          []PodInfo{{Name: "api-5d8f-abc", Restarts: 3, LastRestartReason: "OOMKilled", LastRestartTime: &finished}},
+	}
+	if !reflect.DeepEqual(wi, want) {
+		t.Errorf("getWorkloadInfo() = %+v, want %+v", wi, want)
+	}
+}
+
+func Test_imageTag(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "reportportal/service-api:5.11.0", want: "5.11.0"},
+		{image: "registry:5000/reportportal/service-api", want: "latest"},
+		{image: "registry:5000/reportportal/service-api:5.11.0@sha256:abc", want: "5.11.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := imageTag(tt.image); got != tt.want {
+				t.Errorf("imageTag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
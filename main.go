@@ -1,40 +1,115 @@
 package main
 
 import (
-	"net/http"
-	"time"
+	"context"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/reportportal/commons-go/v5/commons"
 	"github.com/reportportal/commons-go/v5/conf"
 	"github.com/reportportal/commons-go/v5/server"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/auth"
+	"github.com/reportportal/service-index/badge"
+	"github.com/reportportal/service-index/cache"
+	"github.com/reportportal/service-index/compat"
+	"github.com/reportportal/service-index/dependency"
+	"github.com/reportportal/service-index/history"
 	"github.com/reportportal/service-index/k8s"
+	"github.com/reportportal/service-index/maintenance"
+	"github.com/reportportal/service-index/monitor"
+	"github.com/reportportal/service-index/notify"
+	"github.com/reportportal/service-index/probe"
+	"github.com/reportportal/service-index/redact"
+	"github.com/reportportal/service-index/throttle"
 	"github.com/reportportal/service-index/traefik"
 )
 
-const httpClientTimeout = 5 * time.Second
+// config holds settings of the service
+type config struct {
+	*conf.ServerConfig
+	K8sMode               bool   `env:"K8S_MODE"           envDefault:"false"`
+	K8sWorkloadInfo       bool   `env:"K8S_WORKLOAD_INFO"  envDefault:"false"`
+	K8sComponentsCRD      bool   `env:"K8S_COMPONENTS_CRD" envDefault:"false"`
+	K8sHealthEvents       bool   `env:"K8S_HEALTH_EVENTS"  envDefault:"false"`
+	TraefikV2Mode         bool   `env:"TRAEFIK_V2_MODE"    envDefault:"false"`
+	TraefikContainerBased bool   `env:"TRAEFIK_CONTAINER"  envDefault:"true"`
+	UsePathPrefix         bool   `env:"USE_PATH_PREFIX"    envDefault:"false"`
+	TraefikLbURL          string `env:"LB_URL"             envDefault:"http://localhost:8081"`
+	LogLevel              string `env:"LOG_LEVEL"          envDefault:"info"`
+	Path                  string `env:"RESOURCE_PATH"      envDefault:""`
+	Probe                 probe.Config
+	Dependencies          dependency.Config
+	Compatibility         compat.Config
+	Hysteresis            aggregator.HysteresisConfig
+	Monitor               monitor.Config
+	History               history.Config
+	Webhooks              notify.Config
+	Badge                 badge.Config
+	Maintenance           maintenance.Config
+	Auth                  auth.Config
+	Redaction             redact.Config
+	Throttle              throttle.Config
+	Cache                 cache.Config
+}
 
 func main() {
-	cfg := conf.EmptyConfig()
-
-	rpCfg := struct {
-		*conf.ServerConfig
-		K8sMode               bool   `env:"K8S_MODE"          envDefault:"false"`
-		TraefikV2Mode         bool   `env:"TRAEFIK_V2_MODE"   envDefault:"false"`
-		TraefikContainerBased bool   `env:"TRAEFIK_CONTAINER" envDefault:"true"`
-		UsePathPrefix         bool   `env:"USE_PATH_PREFIX"   envDefault:"false"`
-		TraefikLbURL          string `env:"LB_URL"            envDefault:"http://localhost:8081"`
-		LogLevel              string `env:"LOG_LEVEL"         envDefault:"info"`
-		Path                  string `env:"RESOURCE_PATH"     envDefault:""`
-	}{
-		ServerConfig: cfg,
-	}
-
-	err := conf.LoadConfig(&rpCfg)
+	rpCfg := loadConfig()
+
+	info := commons.GetBuildInfo()
+	info.Name = "Index Service"
+
+	srv := server.New(rpCfg.ServerConfig, info)
+
+	if err := rpCfg.Probe.Load(); nil != err {
+		log.Fatalf("Incorrect probe config: %v", err)
+	}
+	probes, err := probe.NewClient(&rpCfg.Probe)
+	if nil != err {
+		log.Fatalf("Unable to create probe client: %v", err)
+	}
+	matrix, err := compat.LoadMatrix(rpCfg.Compatibility.File)
+	if nil != err {
+		log.Fatalf("Incorrect compatibility matrix: %v", err)
+	}
+
+	mode := maintenance.New(&rpCfg.Maintenance)
+	aggreg := buildAggregator(rpCfg, probes, matrix, mode)
+	mon, hist := startMonitor(rpCfg, aggreg, probes)
+
+	maint, err := maintenance.NewHandler(mode)
+	if nil != err {
+		log.Fatalf("Incorrect maintenance config: %v", err)
+	}
+	authn, err := auth.New(&rpCfg.Auth, probes.HTTP())
+	if nil != err {
+		log.Fatalf("Incorrect auth config: %v", err)
+	}
+	badges, err := badge.New(aggreg, mon, &rpCfg.Badge)
+	if nil != err {
+		log.Fatalf("Incorrect badge config: %v", err)
+	}
+
+	rt := &routes{
+		path:    rpCfg.Path,
+		aggreg:  aggreg,
+		matrix:  matrix,
+		mon:     mon,
+		hist:    hist,
+		authn:   authn,
+		badges:  badges,
+		maint:   maint,
+		limiter: throttle.New(&rpCfg.Throttle),
+		cached:  cache.New(&rpCfg.Cache).Handler(cacheVariant(authn)),
+	}
+	srv.WithRouter(rt.register)
+	srv.StartServer()
+}
+
+// loadConfig loads settings from environment and applies the log level
+func loadConfig() *config {
+	rpCfg := &config{ServerConfig: conf.EmptyConfig()}
+	err := conf.LoadConfig(rpCfg)
 	if nil != err {
 		log.Fatalf("Cannot load config %v", err)
 	}
@@ -44,50 +119,106 @@ func main() {
 	}
 	log.SetLevel(ll)
 
-	info := commons.GetBuildInfo()
-	info.Name = "Index Service"
+	return rpCfg
+}
 
-	srv := server.New(rpCfg.ServerConfig, info)
+// buildAggregator creates aggregator of discovered services along with dependencies,
+// decorated by redaction, hysteresis, compatibility check, maintenance mode and Kubernetes events
+func buildAggregator(
+	rpCfg *config,
+	probes *probe.Client,
+	matrix *compat.Matrix,
+	mode *maintenance.Mode,
+) aggregator.Aggregator {
+	aggreg, k8sAggreg := discoveryAggregator(rpCfg, probes)
+
+	checkers, err := dependency.NewCheckers(&rpCfg.Dependencies, probes.HTTP())
+	if nil != err {
+		log.Fatalf("Incorrect dependencies config: %v", err)
+	}
+	aggreg = dependency.NewAggregator(aggreg, checkers, probes)
+	redactor, err := rpCfg.Redaction.Load()
+	if nil != err {
+		log.Fatalf("Incorrect redaction config: %v", err)
+	}
+	aggreg = redact.NewAggregator(aggreg, redactor)
+	// smoothed statuses advance in rounds of background checks only
+	if rpCfg.Monitor.Interval > 0 {
+		aggreg = aggregator.NewHysteresis(aggreg, &rpCfg.Hysteresis)
+	} else if rpCfg.Hysteresis.Enabled() {
+		log.Fatal("Health thresholds and flap detection require background checks, MONITOR_INTERVAL must be positive")
+	}
+	aggreg = aggregator.NewCoalescing(aggreg)
+	if rpCfg.Compatibility.Health {
+		aggreg = compat.NewAggregator(aggreg, matrix, rpCfg.Compatibility.InfoTTL)
+	}
+	aggreg = maintenance.NewAggregator(aggreg, mode)
+	if k8sAggreg != nil {
+		// events are recorded of smoothed statuses, failures during maintenance are recorded as MAINTENANCE
+		aggreg = k8sAggreg.WithEvents(aggreg)
+	}
+
+	return aggreg
+}
 
+// discoveryAggregator creates aggregator of services discovered either in Kubernetes or via Traefik API,
+// Kubernetes aggregator is returned as well to record events
+func discoveryAggregator(rpCfg *config, probes *probe.Client) (aggregator.Aggregator, *k8s.Aggregator) {
 	log.Infof("K8S mode enabled: %t", rpCfg.K8sMode)
-	var aggreg aggregator.Aggregator
-	if rpCfg.K8sMode {
-		aggreg, err = k8s.NewAggregator(httpClientTimeout)
-		if nil != err {
-			log.Fatalf("Incorrect K8S config %s", err.Error())
-		}
-	} else {
-		aggreg = traefik.NewAggregator(
+	if !rpCfg.K8sMode {
+		return traefik.NewAggregator(
 			rpCfg.TraefikLbURL,
 			rpCfg.TraefikV2Mode,
 			rpCfg.TraefikContainerBased,
 			rpCfg.UsePathPrefix,
-			httpClientTimeout,
-		)
-	}
-
-	srv.WithRouter(func(router *chi.Mux) {
-		router.Use(middleware.Logger)
-		router.NotFound(func(w http.ResponseWriter, rq *http.Request) {
-			http.Redirect(w, rq, rpCfg.Path+"/ui/#notfound", http.StatusFound)
-		})
-
-		router.HandleFunc(rpCfg.Path+"/composite/info", func(w http.ResponseWriter, r *http.Request) {
-			if err := server.WriteJSON(http.StatusOK, aggreg.AggregateInfo(), w); nil != err {
-				log.Error(err)
-			}
-		})
-		router.HandleFunc(rpCfg.Path+"/composite/health", func(w http.ResponseWriter, r *http.Request) {
-			if err := server.WriteJSON(http.StatusOK, aggreg.AggregateHealth(), w); nil != err {
-				log.Error(err)
-			}
-		})
-		router.HandleFunc(rpCfg.Path+"/", func(w http.ResponseWriter, r *http.Request) {
-			http.Redirect(w, r, rpCfg.Path+"/ui/", http.StatusFound)
-		})
-		router.HandleFunc(rpCfg.Path+"/ui", func(w http.ResponseWriter, r *http.Request) {
-			http.Redirect(w, r, rpCfg.Path+"/ui/", http.StatusFound)
-		})
-	})
-	srv.StartServer()
+			probes,
+		), nil
+	}
+
+	k8sAggreg, err := k8s.NewAggregator(
+		probes,
+		rpCfg.K8sWorkloadInfo,
+		rpCfg.K8sComponentsCRD,
+		rpCfg.K8sHealthEvents,
+	)
+	if nil != err {
+		log.Fatalf("Incorrect K8S config %s", err.Error())
+	}
+
+	return k8sAggreg, k8sAggreg
+}
+
+// startMonitor starts background checks feeding health history and webhooks, nil is returned if the checks are disabled
+func startMonitor(rpCfg *config, aggreg aggregator.Aggregator, probes *probe.Client) (*monitor.Monitor, *history.History) {
+	if rpCfg.Monitor.Interval <= 0 {
+		if rpCfg.History.File != "" || rpCfg.Webhooks.File != "" {
+			log.Fatal("Health history and webhooks require background checks, MONITOR_INTERVAL must be positive")
+		}
+		log.Warn("Background checks are disabled, health stream, history and webhooks aren't available")
+
+		return nil, nil
+	}
+
+	mon, err := monitor.New(aggreg, &rpCfg.Monitor)
+	if nil != err {
+		log.Fatalf("Incorrect monitor config: %v", err)
+	}
+	hist, err := history.New(&rpCfg.History)
+	if nil != err {
+		log.Fatalf("Unable to init health history: %v", err)
+	}
+	mon.AddListener(hist)
+
+	webhooks, err := rpCfg.Webhooks.Load()
+	if nil != err {
+		log.Fatalf("Incorrect webhooks config: %v", err)
+	}
+	notifier, err := notify.New(webhooks, probes.HTTP())
+	if nil != err {
+		log.Fatalf("Unable to init webhooks: %v", err)
+	}
+	mon.AddListener(notifier)
+	mon.Start(context.Background())
+
+	return mon, hist
 }
@@ -0,0 +1,64 @@
+package maintenance
+
+import (
+	"context"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+// Aggregator reports services which aren't UP as being under maintenance while the mode is active
+type Aggregator struct {
+	delegate aggregator.Aggregator
+	mode     *Mode
+}
+
+// NewAggregator wraps the aggregator with maintenance mode
+func NewAggregator(delegate aggregator.Aggregator, mode *Mode) *Aggregator {
+	return &Aggregator{delegate: delegate, mode: mode}
+}
+
+// AggregateInfo collects information from info endpoints
+func (a *Aggregator) AggregateInfo(ctx context.Context) map[string]interface{} {
+	return a.delegate.AggregateInfo(ctx)
+}
+
+// Services returns names of discovered services
+func (a *Aggregator) Services(ctx context.Context) ([]string, error) {
+	return a.delegate.Services(ctx)
+}
+
+// Discover resolves nodes and describes the result along with the ignored objects
+func (a *Aggregator) Discover(ctx context.Context) *aggregator.Discovery {
+	return a.delegate.Discover(ctx)
+}
+
+// AggregateHealth aggregates health of services replacing failures with MAINTENANCE status during maintenance
+func (a *Aggregator) AggregateHealth(ctx context.Context) map[string]interface{} {
+	health := a.delegate.AggregateHealth(ctx)
+	if !a.mode.Active() {
+		return health
+	}
+
+	for name, entry := range health {
+		e, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		observed, _ := e["status"].(string)
+		if observed == aggregator.StatusUp {
+			continue
+		}
+
+		res := make(map[string]interface{}, len(e)+1)
+		for k, v := range e {
+			res[k] = v
+		}
+		res["status"] = aggregator.StatusMaintenance
+		if _, ok := e["observedStatus"]; !ok && observed != "" {
+			res["observedStatus"] = observed
+		}
+		health[name] = res
+	}
+
+	return health
+}
@@ -0,0 +1,151 @@
+package maintenance
+
+import (
+	"bytes"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reportportal/commons-go/v5/server"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/reportportal/service-index/auth"
+)
+
+var (
+	errIncorrectWindow = errors.New("end of maintenance window must follow its start")
+	errAPIDisabled     = errors.New("maintenance API is disabled")
+	errUnauthorized    = errors.New("incorrect maintenance token")
+)
+
+//go:embed maintenance.html
+var defaultPage string
+
+// pageData is a data of maintenance page template
+type pageData struct {
+	Message string
+	End     *time.Time
+}
+
+// Handler serves maintenance API and maintenance page
+type Handler struct {
+	mode  *Mode
+	token []byte
+	page  *template.Template
+}
+
+// NewHandler creates handler of the mode, custom page template and API token are read from the configured files
+func NewHandler(mode *Mode) (*Handler, error) {
+	h := &Handler{mode: mode}
+
+	text := defaultPage
+	if mode.cfg.Page != "" {
+		data, err := os.ReadFile(mode.cfg.Page)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read maintenance page: %w", err)
+		}
+		text = string(data)
+	}
+	page, err := template.New("maintenance").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect maintenance page: %w", err)
+	}
+	h.page = page
+
+	if mode.cfg.TokenFile != "" {
+		data, err := os.ReadFile(mode.cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read maintenance token: %w", err)
+		}
+		h.token = []byte(strings.TrimSpace(string(data)))
+	}
+
+	return h, nil
+}
+
+// API serves maintenance status, PUT enables maintenance mode within the window of request body, DELETE disables it
+func (h *Handler) API() http.Handler {
+	return server.Handler{H: func(w http.ResponseWriter, r *http.Request) error {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			if err := h.authorize(r); err != nil {
+				return err
+			}
+			var win Window
+			if err := json.NewDecoder(r.Body).Decode(&win); err != nil {
+				return server.ToStatusError(http.StatusBadRequest, fmt.Errorf("incorrect maintenance window: %w", err))
+			}
+			if err := h.mode.Set(&win); err != nil {
+				return server.ToStatusError(http.StatusBadRequest, err)
+			}
+			log.Infof("Maintenance mode is set via API: %+v", win)
+		case http.MethodDelete:
+			if err := h.authorize(r); err != nil {
+				return err
+			}
+			h.mode.Clear()
+			log.Info("Maintenance mode is cleared via API")
+		default:
+			return server.ToStatusError(http.StatusMethodNotAllowed, fmt.Errorf("method %s isn't allowed", r.Method))
+		}
+
+		return server.WriteJSON(http.StatusOK, h.mode.Status(), w)
+	}}
+}
+
+// authorize checks bearer token of the request, admins authenticated by the auth middleware are allowed as well
+func (h *Handler) authorize(r *http.Request) error {
+	if p := auth.PrincipalFrom(r.Context()); p != nil && p.Admin {
+		return nil
+	}
+	if len(h.token) == 0 {
+		return server.ToStatusError(http.StatusForbidden, errAPIDisabled)
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), h.token) != 1 {
+		return server.ToStatusError(http.StatusUnauthorized, errUnauthorized)
+	}
+
+	return nil
+}
+
+// Page serves maintenance page while the mode is active, otherwise the request is passed to the next handler
+func (h *Handler) Page(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := h.mode.Status()
+		if !st.Active {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := h.page.Execute(&buf, &pageData{Message: st.Message, End: st.End}); err != nil {
+			log.Errorf("Unable to render maintenance page: %v", err)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if st.End != nil {
+			if d := time.Until(*st.End); d > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(d.Seconds())+1))
+			}
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if _, err := buf.WriteTo(w); err != nil {
+			log.Error(err)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+// Sources maintenance mode is enabled by
+const (
+	SourceAPI  = "api"
+	SourceFile = "file"
+	SourceEnv  = "env"
+)
+
+// Config holds settings of maintenance mode
+type Config struct {
+	// Enabled turns maintenance mode on, within the schedule if it's configured
+	Enabled bool `env:"MAINTENANCE_MODE" envDefault:"false"`
+	// Start and End schedule maintenance window in RFC3339 format, any of them enables maintenance mode
+	Start   time.Time `env:"MAINTENANCE_START"`
+	End     time.Time `env:"MAINTENANCE_END"`
+	Message string    `env:"MAINTENANCE_MESSAGE" envDefault:""`
+	// File is a flag file enabling maintenance mode while it exists, it may declare message and schedule in YAML or JSON
+	File string `env:"MAINTENANCE_FILE" envDefault:""`
+	// Page is an optional HTML template served instead of UI redirects during maintenance
+	Page string `env:"MAINTENANCE_PAGE" envDefault:""`
+	// TokenFile contains a bearer token required to change maintenance mode via API, API changes are disabled if empty
+	TokenFile string `env:"MAINTENANCE_TOKEN_FILE" envDefault:""`
+}
+
+// Window is a maintenance period, open bounds mean it lasts until the mode is switched off
+type Window struct {
+	Message string     `json:"message,omitempty"`
+	Start   *time.Time `json:"start,omitempty"`
+	End     *time.Time `json:"end,omitempty"`
+}
+
+// Active reports whether the window covers the moment
+func (w *Window) Active(now time.Time) bool {
+	if w == nil {
+		return false
+	}
+
+	return (w.Start == nil || !now.Before(*w.Start)) && (w.End == nil || now.Before(*w.End))
+}
+
+// Status describes current maintenance mode
+type Status struct {
+	Active bool `json:"active"`
+	// Source is where the active or the upcoming window comes from
+	Source string `json:"source,omitempty"`
+	*Window
+}
+
+// Mode resolves maintenance windows set via API, the flag file and the environment, in this order of priority
+type Mode struct {
+	cfg *Config
+	env *Window
+	now func() time.Time
+
+	mu      sync.RWMutex
+	api     *Window
+	file    *Window
+	fileMod time.Time
+}
+
+// New creates maintenance mode of the config
+func New(cfg *Config) *Mode {
+	m := &Mode{cfg: cfg, now: time.Now}
+	if cfg.Enabled || !cfg.Start.IsZero() || !cfg.End.IsZero() {
+		m.env = &Window{Message: cfg.Message}
+		if !cfg.Start.IsZero() {
+			m.env.Start = &cfg.Start
+		}
+		if !cfg.End.IsZero() {
+			m.env.End = &cfg.End
+		}
+	}
+
+	return m
+}
+
+// Active reports whether services are under maintenance right now
+func (m *Mode) Active() bool {
+	return m.Status().Active
+}
+
+// Status returns the active window or the upcoming one, if any
+func (m *Mode) Status() *Status {
+	now := m.now()
+	windows := []struct {
+		source string
+		w      *Window
+	}{{SourceAPI, m.apiWindow()}, {SourceFile, m.fileWindow()}, {SourceEnv, m.env}}
+
+	var upcoming *Status
+	for _, sw := range windows {
+		if sw.w.Active(now) {
+			return &Status{Active: true, Source: sw.source, Window: sw.w}
+		}
+		if upcoming == nil && sw.w != nil && sw.w.Start != nil && now.Before(*sw.w.Start) {
+			upcoming = &Status{Source: sw.source, Window: sw.w}
+		}
+	}
+	if upcoming != nil {
+		return upcoming
+	}
+
+	return &Status{}
+}
+
+// Set enables maintenance mode within the window, it overrides the file and the environment
+func (m *Mode) Set(w *Window) error {
+	if w.Start != nil && w.End != nil && !w.End.After(*w.Start) {
+		return errIncorrectWindow
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.api = w
+
+	return nil
+}
+
+// Clear disables maintenance mode set via API
+func (m *Mode) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.api = nil
+}
+
+func (m *Mode) apiWindow() *Window {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.api
+}
+
+// fileWindow returns window declared by the flag file, the file is parsed again once it's modified
+func (m *Mode) fileWindow() *Window {
+	if m.cfg.File == "" {
+		return nil
+	}
+	fi, err := os.Stat(m.cfg.File)
+	if err != nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.file != nil && fi.ModTime().Equal(m.fileMod) {
+		return m.file
+	}
+
+	w, err := readWindow(m.cfg.File)
+	if err != nil {
+		// the flag is still taken into account even though its content is incorrect
+		log.Errorf("Incorrect maintenance file: %v", err)
+		w = &Window{}
+	}
+	m.file, m.fileMod = w, fi.ModTime()
+
+	return w
+}
+
+func readWindow(file string) (*Window, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read maintenance file: %w", err)
+	}
+	w := &Window{}
+	if err := yaml.UnmarshalStrict(data, w); err != nil {
+		return nil, fmt.Errorf("unable to parse maintenance file: %w", err)
+	}
+
+	return w, nil
+}
@@ -0,0 +1,147 @@
+package maintenance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/aggregator/aggregatortest"
+	"github.com/reportportal/service-index/internal/testutil"
+)
+
+func TestMode_Status(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+	flag := filepath.Join(t.TempDir(), "maintenance")
+
+	tests := []struct {
+		name       string
+		cfg        *Config
+		withFile   bool
+		file       string
+		api        *Window
+		wantActive bool
+		wantSource string
+	}{
+		{name: "disabled", cfg: &Config{File: flag}},
+		{name: "env", cfg: &Config{Enabled: true}, wantActive: true, wantSource: SourceEnv},
+		{name: "env schedule", cfg: &Config{Start: before, End: after}, wantActive: true, wantSource: SourceEnv},
+		{name: "env upcoming", cfg: &Config{Start: after}, wantSource: SourceEnv},
+		{name: "env finished", cfg: &Config{End: before}},
+		{name: "empty file", cfg: &Config{File: flag}, withFile: true, wantActive: true, wantSource: SourceFile},
+		{name: "file schedule", cfg: &Config{File: flag}, withFile: true, file: "message: upgrade\nend: " + after.Format(time.RFC3339), wantActive: true, wantSource: SourceFile},
+		{name: "api overrides", cfg: &Config{Enabled: true, File: flag}, withFile: true, file: "message: upgrade", api: &Window{End: &after}, wantActive: true, wantSource: SourceAPI},
+		{name: "api upcoming", cfg: &Config{}, api: &Window{Start: &after}, wantSource: SourceAPI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Remove(flag)
+			if tt.withFile {
+				if err := os.WriteFile(flag, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m := New(tt.cfg)
+			m.now = func() time.Time { return now }
+			if tt.api != nil {
+				if err := m.Set(tt.api); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			st := m.Status()
+			if st.Active != tt.wantActive || st.Source != tt.wantSource {
+				t.Errorf("Status() = %+v, want active %v from %q", st, tt.wantActive, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestMode_Set(t *testing.T) {
+	start, end := time.Now(), time.Now().Add(-time.Minute)
+	if err := New(&Config{}).Set(&Window{Start: &start, End: &end}); err == nil {
+		t.Error("Set() error = nil, want incorrect window")
+	}
+}
+
+func TestAggregator_AggregateHealth(t *testing.T) {
+	mode := New(&Config{})
+	a := NewAggregator(&aggregatortest.Stub{Health: aggregatortest.Statuses(map[string]string{
+		"api": aggregator.StatusUp,
+		"uat": aggregator.StatusDown,
+	})}, mode)
+	if uat := a.AggregateHealth(context.Background())["uat"].(map[string]interface{}); uat["status"] != aggregator.StatusDown {
+		t.Errorf("AggregateHealth() uat = %v, want DOWN out of maintenance", uat)
+	}
+
+	if err := mode.Set(&Window{}); err != nil {
+		t.Fatal(err)
+	}
+	health := a.AggregateHealth(context.Background())
+	if api := health["api"].(map[string]interface{}); api["status"] != aggregator.StatusUp {
+		t.Errorf("AggregateHealth() api = %v, want UP", api)
+	}
+	uat := health["uat"].(map[string]interface{})
+	if uat["status"] != aggregator.StatusMaintenance || uat["observedStatus"] != aggregator.StatusDown {
+		t.Errorf("AggregateHealth() uat = %v, want MAINTENANCE", uat)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h, err := NewHandler(New(&Config{TokenFile: testutil.WriteSecret(t, "token", "t0ken")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect := h.Page(http.RedirectHandler("/ui/", http.StatusFound))
+
+	call := func(method, token, body string) int {
+		rq := httptest.NewRequest(method, "/composite/maintenance", strings.NewReader(body))
+		if token != "" {
+			rq.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		h.API().ServeHTTP(rr, rq)
+
+		return rr.Code
+	}
+
+	if code := call(http.MethodPut, "wrong", `{}`); code != http.StatusUnauthorized {
+		t.Errorf("PUT with wrong token code = %d", code)
+	}
+	if code := call(http.MethodPut, "t0ken", `{"message": "Upgrade to <5.12>"}`); code != http.StatusOK {
+		t.Errorf("PUT code = %d", code)
+	}
+
+	rr := httptest.NewRecorder()
+	redirect.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusServiceUnavailable || !strings.Contains(rr.Body.String(), "Upgrade to &lt;5.12&gt;") {
+		t.Errorf("Page() = %d %s, want maintenance page", rr.Code, rr.Body.String())
+	}
+
+	if code := call(http.MethodDelete, "t0ken", ""); code != http.StatusOK {
+		t.Errorf("DELETE code = %d", code)
+	}
+	rr = httptest.NewRecorder()
+	redirect.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusFound {
+		t.Errorf("Page() code = %d, want redirect out of maintenance", rr.Code)
+	}
+}
+
+func TestHandler_APIDisabled(t *testing.T) {
+	h, err := NewHandler(New(&Config{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	h.API().ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/composite/maintenance", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("DELETE code = %d, want forbidden without token", rr.Code)
+	}
+}
@@ -0,0 +1,247 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+// Event types
+const (
+	EventSnapshot  = "snapshot"
+	EventChange    = "change"
+	EventHeartbeat = "heartbeat"
+)
+
+var errNonPositiveInterval = errors.New("check and heartbeat intervals must be positive")
+
+// replaySize is a number of recent change events kept for reconnecting subscribers
+const replaySize = 256
+
+// Config holds settings of background health checks
+type Config struct {
+	// Interval between background health checks, zero disables them along with the health stream
+	Interval time.Duration `env:"MONITOR_INTERVAL" envDefault:"30s"`
+	// Heartbeat is an interval of keep-alive messages sent to stream subscribers
+	Heartbeat time.Duration `env:"STREAM_HEARTBEAT" envDefault:"15s"`
+}
+
+// Event is either a full health snapshot or a set of services whose status changed.
+// Removed services are reported with null entries in change events
+type Event struct {
+	ID       uint64                 `json:"id"`
+	Type     string                 `json:"type"`
+	Time     time.Time              `json:"time"`
+	Services map[string]interface{} `json:"services,omitempty"`
+}
+
+// Listener is notified about status changes seen by background checks
+type Listener interface {
+	Observe(ev Event)
+}
+
+// CheckListener is a listener which is also notified about results of every background check
+type CheckListener interface {
+	Listener
+	ObserveCheck(health map[string]interface{}, at time.Time)
+}
+
+// Monitor periodically checks composite health and notifies subscribers about status changes
+type Monitor struct {
+	aggreg aggregator.Aggregator
+	cfg    *Config
+
+	mu        sync.RWMutex
+	snapshot  map[string]interface{}
+	checkedAt time.Time
+	seq       uint64
+	recent    []Event
+	subs      map[chan Event]struct{}
+	listeners []Listener
+}
+
+// New creates monitor of the aggregator, check and heartbeat intervals must be positive
+func New(aggreg aggregator.Aggregator, cfg *Config) (*Monitor, error) {
+	if cfg.Interval <= 0 || cfg.Heartbeat <= 0 {
+		return nil, errNonPositiveInterval
+	}
+
+	return &Monitor{
+		aggreg:   aggreg,
+		cfg:      cfg,
+		snapshot: map[string]interface{}{},
+		subs:     map[chan Event]struct{}{},
+	}, nil
+}
+
+// AddListener registers listener of status changes, it must be called before the monitor is started
+func (m *Monitor) AddListener(l Listener) {
+	m.listeners = append(m.listeners, l)
+}
+
+// Start runs background checks until the context is done
+func (m *Monitor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			m.Check(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Check aggregates health in a check round and publishes changes since the previous check
+func (m *Monitor) Check(ctx context.Context) {
+	health := m.aggreg.AggregateHealth(aggregator.WithCheckRound(ctx))
+	ev, changed := m.publish(health)
+	for _, l := range m.listeners {
+		if changed {
+			l.Observe(ev)
+		}
+		if cl, ok := l.(CheckListener); ok {
+			cl.ObserveCheck(health, ev.Time)
+		}
+	}
+}
+
+// publish updates the snapshot and sends change event to subscribers, if anything changed
+func (m *Monitor) publish(health map[string]interface{}) (Event, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changed := diff(m.snapshot, health)
+	m.snapshot = health
+	m.checkedAt = time.Now()
+	if len(changed) == 0 {
+		return Event{Time: m.checkedAt}, false
+	}
+
+	m.seq++
+	ev := Event{ID: m.seq, Type: EventChange, Time: m.checkedAt, Services: changed}
+	m.recent = append(m.recent, ev)
+	if len(m.recent) > replaySize {
+		m.recent = m.recent[len(m.recent)-replaySize:]
+	}
+	log.Debugf("Health of %d services changed", len(changed))
+
+	for ch := range m.subs {
+		select {
+		case ch <- ev:
+		default:
+			// slow subscriber is dropped and will resync on reconnect
+			delete(m.subs, ch)
+			close(ch)
+		}
+	}
+
+	return ev, true
+}
+
+// Snapshot returns the latest checked health and time of the check
+func (m *Monitor) Snapshot() (map[string]interface{}, time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cp := make(map[string]interface{}, len(m.snapshot))
+	for k, v := range m.snapshot {
+		cp[k] = v
+	}
+
+	return cp, m.checkedAt
+}
+
+// Interval returns period the snapshot is refreshed with
+func (m *Monitor) Interval() time.Duration {
+	return m.cfg.Interval
+}
+
+// Subscribe returns events a subscriber should start with followed by a channel of further changes.
+// If the last seen event is still buffered, the missed changes are replayed, otherwise a full snapshot is sent.
+// The channel is closed once the subscriber falls behind or cancels the subscription
+func (m *Monitor) Subscribe(lastID uint64, hasLastID bool) ([]Event, <-chan Event, func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var initial []Event
+	if hasLastID && m.canReplay(lastID) {
+		for _, ev := range m.recent {
+			if ev.ID > lastID {
+				initial = append(initial, ev)
+			}
+		}
+	} else {
+		services := make(map[string]interface{}, len(m.snapshot))
+		for k, v := range m.snapshot {
+			services[k] = v
+		}
+		initial = []Event{{ID: m.seq, Type: EventSnapshot, Time: m.checkedAt, Services: services}}
+	}
+
+	ch := make(chan Event, replaySize)
+	m.subs[ch] = struct{}{}
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if _, ok := m.subs[ch]; ok {
+				delete(m.subs, ch)
+				close(ch)
+			}
+		})
+	}
+
+	return initial, ch, cancel
+}
+
+// canReplay checks whether all the events following the last seen one are still buffered
+func (m *Monitor) canReplay(lastID uint64) bool {
+	if lastID > m.seq {
+		return false
+	}
+	if lastID == m.seq {
+		return true
+	}
+
+	return len(m.recent) > 0 && m.recent[0].ID <= lastID+1
+}
+
+// diff returns entries of services whose status changed, appeared or disappeared
+func diff(prev, next map[string]interface{}) map[string]interface{} {
+	changed := map[string]interface{}{}
+	for name, entry := range next {
+		old, ok := prev[name]
+		if !ok || StatusOf(old) != StatusOf(entry) {
+			changed[name] = entry
+		}
+	}
+	for name := range prev {
+		if _, ok := next[name]; !ok {
+			changed[name] = nil
+		}
+	}
+
+	return changed
+}
+
+// StatusOf extracts status of the health entry, entries without status are UNKNOWN
+func StatusOf(entry interface{}) string {
+	e, _ := entry.(map[string]interface{})
+	if s, ok := e["status"].(string); ok {
+		return s
+	}
+
+	return aggregator.StatusUnknown
+}
@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/aggregator/aggregatortest"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "valid", cfg: Config{Interval: time.Minute, Heartbeat: time.Second}},
+		{name: "zero heartbeat", cfg: Config{Interval: time.Minute}, wantErr: true},
+		{name: "negative heartbeat", cfg: Config{Interval: time.Minute, Heartbeat: -time.Second}, wantErr: true},
+		{name: "zero interval", cfg: Config{Heartbeat: time.Second}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(&aggregatortest.Stub{}, &tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMonitor_Subscribe(t *testing.T) {
+	aggr := &aggregatortest.Stub{}
+	m, err := New(aggr, &Config{Interval: time.Minute, Heartbeat: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aggr.SetHealth(aggregatortest.Statuses(map[string]string{"api": aggregator.StatusUp, "uat": aggregator.StatusUp}))
+	m.Check(context.Background())
+
+	initial, events, cancel := m.Subscribe(0, false)
+	defer cancel()
+	if len(initial) != 1 || initial[0].Type != EventSnapshot || len(initial[0].Services) != 2 {
+		t.Fatalf("Subscribe() initial = %+v, want snapshot of 2 services", initial)
+	}
+
+	aggr.SetHealth(aggregatortest.Statuses(map[string]string{"api": aggregator.StatusDown, "uat": aggregator.StatusUp}))
+	m.Check(context.Background())
+	m.Check(context.Background())
+
+	ev := <-events
+	if ev.Type != EventChange || ev.ID != 2 || len(ev.Services) != 1 || ev.Services["api"] == nil {
+		t.Errorf("change event = %+v, want api only", ev)
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event %+v without status change", ev)
+	default:
+	}
+
+}
+
+func TestMonitor_Subscribe_lastEventID(t *testing.T) {
+	aggr := &aggregatortest.Stub{}
+	m, err := New(aggr, &Config{Interval: time.Minute, Heartbeat: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aggr.SetHealth(aggregatortest.Statuses(map[string]string{"api": aggregator.StatusUp}))
+	m.Check(context.Background())
+	aggr.SetHealth(aggregatortest.Statuses(map[string]string{"api": aggregator.StatusDown}))
+	m.Check(context.Background())
+
+	replayed, _, cancelReplay := m.Subscribe(1, true)
+	defer cancelReplay()
+	if len(replayed) != 1 || replayed[0].ID != 2 {
+		t.Errorf("Subscribe(1) = %+v, want replay of event 2", replayed)
+	}
+	resynced, _, cancelResync := m.Subscribe(42, true)
+	defer cancelResync()
+	if len(resynced) != 1 || resynced[0].Type != EventSnapshot {
+		t.Errorf("Subscribe(42) = %+v, want snapshot", resynced)
+	}
+}
+
+func TestMonitor_ServeSSE(t *testing.T) {
+	aggr := &aggregatortest.Stub{}
+	aggr.SetHealth(aggregatortest.Statuses(map[string]string{"api": aggregator.StatusUp}))
+	m, err := New(aggr, &Config{Interval: time.Minute, Heartbeat: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Check(context.Background())
+
+	srv := httptest.NewServer(http.HandlerFunc(m.ServeSSE))
+	defer srv.Close()
+
+	rs, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Body.Close()
+	if ct := rs.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %v", ct)
+	}
+
+	lines := bufio.NewScanner(rs.Body)
+	expect := func(prefix string) {
+		t.Helper()
+		for lines.Scan() {
+			if strings.HasPrefix(lines.Text(), prefix) {
+				return
+			}
+		}
+		t.Fatalf("stream ended before %q", prefix)
+	}
+
+	expect("event: snapshot")
+	expect(": heartbeat")
+	aggr.SetHealth(aggregatortest.Statuses(map[string]string{"api": aggregator.StatusDown}))
+	m.Check(context.Background())
+	expect("id: 2")
+	expect("event: change")
+}
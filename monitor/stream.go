@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+// lastEventIDParam is a query parameter alternative to Last-Event-ID header, e.g. for WebSocket clients
+const lastEventIDParam = "lastEventId"
+
+// ServeSSE streams health changes as Server-Sent Events.
+// Clients reconnecting with Last-Event-ID header receive the changes they missed
+func (m *Monitor) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+
+		return
+	}
+
+	lastID, hasLastID := lastEventID(r)
+	initial, events, cancel := m.Subscribe(lastID, hasLastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", m.cfg.Heartbeat.Milliseconds())
+
+	for _, ev := range initial {
+		if err := writeSSE(w, ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	m.streamSSE(r, w, flusher, events)
+}
+
+// streamSSE writes events along with heartbeats until either the subscription or the request is over
+func (m *Monitor) streamSSE(r *http.Request, w http.ResponseWriter, flusher http.Flusher, events <-chan Event) {
+	heartbeat := time.NewTicker(m.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, ev); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// WebSocketHandler streams health changes as JSON messages over WebSocket
+func (m *Monitor) WebSocketHandler() http.Handler {
+	return websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		lastID, hasLastID := lastEventID(ws.Request())
+		initial, events, cancel := m.Subscribe(lastID, hasLastID)
+		defer cancel()
+
+		// incoming messages aren't expected, reading detects closed connections
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var msg string
+			for websocket.Message.Receive(ws, &msg) == nil {
+			}
+		}()
+
+		for _, ev := range initial {
+			if err := websocket.JSON.Send(ws, ev); err != nil {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(m.cfg.Heartbeat)
+		defer heartbeat.Stop()
+		for {
+			var err error
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				err = websocket.JSON.Send(ws, ev)
+			case t := <-heartbeat.C:
+				err = websocket.JSON.Send(ws, Event{Type: EventHeartbeat, Time: t})
+			case <-closed:
+				return
+			}
+			if err != nil {
+				log.Debugf("Unable to send health event: %v", err)
+
+				return
+			}
+		}
+	})
+}
+
+func writeSSE(w http.ResponseWriter, ev Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("unable to marshal event: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+
+	return err
+}
+
+// lastEventID returns ID of the last event received by the reconnecting client
+func lastEventID(r *http.Request) (uint64, bool) {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get(lastEventIDParam)
+	}
+	if s == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
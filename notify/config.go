@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/reportportal/service-index/probe"
+)
+
+// Payload presets
+const (
+	PresetGeneric    = "generic"
+	PresetSlack      = "slack"
+	PresetTeams      = "teams"
+	PresetMattermost = "mattermost"
+)
+
+// defaultRetries is a number of delivery retries unless declared explicitly
+const defaultRetries = 3
+
+// Config holds settings of webhook notifications
+type Config struct {
+	// File is an optional path to YAML or JSON file declaring webhooks
+	File string `env:"WEBHOOKS_FILE" envDefault:""`
+}
+
+// fileConfig represents content of the webhooks file
+type fileConfig struct {
+	Webhooks []*Webhook `json:"webhooks"`
+}
+
+// Webhook declares an endpoint notified about status changes
+type Webhook struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Preset is a payload format, either generic, slack, teams or mattermost
+	Preset string `json:"preset,omitempty"`
+	// Template is a Go template of JSON payload overriding the preset
+	Template string `json:"template,omitempty"`
+	// SecretFile contains a key payloads are signed with using HMAC-SHA256
+	SecretFile string            `json:"secretFile,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	// Services limits notifications to the listed services, empty means all of them
+	Services []string `json:"services,omitempty"`
+	// Confirmations is a number of consecutive checks a service must stay in the new status before notification
+	Confirmations int `json:"confirmations,omitempty"`
+	// Retries is a number of delivery retries, 3 by default, zero disables them
+	Retries *int `json:"retries,omitempty"`
+	// Timeout of a single delivery attempt
+	Timeout probe.Duration `json:"timeout,omitempty"`
+}
+
+// Load reads webhooks from the configured file, if any
+func (c *Config) Load() ([]*Webhook, error) {
+	if c.File == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(c.File)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read webhooks config: %w", err)
+	}
+	var fc fileConfig
+	if err := yaml.UnmarshalStrict(data, &fc); err != nil {
+		return nil, fmt.Errorf("unable to parse webhooks config: %w", err)
+	}
+	for i, wh := range fc.Webhooks {
+		if wh.Name == "" {
+			wh.Name = fmt.Sprintf("webhook-%d", i)
+		}
+		if err := wh.defaults(); err != nil {
+			return nil, fmt.Errorf("incorrect webhook %s: %w", wh.Name, err)
+		}
+	}
+
+	return fc.Webhooks, nil
+}
+
+// defaults fills in optional settings and checks the webhook is correct
+func (wh *Webhook) defaults() error {
+	if wh.URL == "" {
+		return errNoURL
+	}
+	if wh.Preset == "" {
+		wh.Preset = PresetGeneric
+	}
+	if _, ok := presets[wh.Preset]; !ok && wh.Template == "" {
+		return fmt.Errorf("unknown preset %q", wh.Preset)
+	}
+	if wh.Confirmations <= 0 {
+		wh.Confirmations = 1
+	}
+	if wh.Retries != nil && *wh.Retries < 0 {
+		return errNegativeRetries
+	}
+	if wh.Timeout == 0 {
+		wh.Timeout = probe.Duration(5 * time.Second)
+	}
+
+	return nil
+}
+
+// retries returns number of delivery retries
+func (wh *Webhook) retries() int {
+	if wh.Retries == nil {
+		return defaultRetries
+	}
+
+	return *wh.Retries
+}
+
+// secret reads signing key of the webhook
+func (wh *Webhook) secret() ([]byte, error) {
+	if wh.SecretFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(wh.SecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read webhook secret: %w", err)
+	}
+
+	return []byte(strings.TrimSpace(string(data))), nil
+}
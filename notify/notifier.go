@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/monitor"
+)
+
+// queueSize is a number of notifications waiting for delivery per webhook
+const queueSize = 100
+
+// Notifier sends webhooks once a service stays in a new status for the configured number of checks
+type Notifier struct {
+	hooks []*hook
+}
+
+// hook tracks confirmed statuses of services on behalf of a single webhook
+type hook struct {
+	sender *sender
+	queue  chan *Notification
+
+	mu     sync.Mutex
+	states map[string]*state
+}
+
+// state is a last notified status of a service and a candidate status waiting for confirmation
+type state struct {
+	confirmed string
+	candidate string
+	checks    int
+}
+
+// New creates notifier of the webhooks, delivery workers are started right away
+func New(webhooks []*Webhook, httpClient *http.Client) (*Notifier, error) {
+	n := &Notifier{}
+	for _, wh := range webhooks {
+		s, err := newSender(wh, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		h := &hook{sender: s, queue: make(chan *Notification, queueSize), states: map[string]*state{}}
+		go h.deliver()
+		n.hooks = append(n.hooks, h)
+	}
+
+	return n, nil
+}
+
+// Observe is a no-op, changes are confirmed by results of the checks
+func (n *Notifier) Observe(monitor.Event) {}
+
+// ObserveCheck tracks statuses of the services and notifies about confirmed changes
+func (n *Notifier) ObserveCheck(health map[string]interface{}, at time.Time) {
+	for _, h := range n.hooks {
+		h.observe(health, at)
+	}
+}
+
+func (h *hook) observe(health map[string]interface{}, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	wh := h.sender.wh
+	for service, entry := range health {
+		if len(wh.Services) > 0 && !slices.Contains(wh.Services, service) {
+			continue
+		}
+
+		status := monitor.StatusOf(entry)
+		if status == aggregator.StatusMaintenance {
+			// notifications are suppressed during maintenance, the change is reported once it's over
+			continue
+		}
+		st, ok := h.states[service]
+		if !ok {
+			// statuses seen on startup are taken as they are
+			h.states[service] = &state{confirmed: status}
+
+			continue
+		}
+		if status == st.confirmed {
+			st.candidate, st.checks = "", 0
+
+			continue
+		}
+		if status != st.candidate {
+			st.candidate, st.checks = status, 0
+		}
+		st.checks++
+		if st.checks < wh.Confirmations {
+			continue
+		}
+
+		body, _ := entry.(map[string]interface{})
+		n := &Notification{Service: service, Status: status, Previous: st.confirmed, Time: at, Entry: body}
+		st.confirmed, st.candidate, st.checks = status, "", 0
+		select {
+		case h.queue <- n:
+		default:
+			log.Errorf("Webhook %s queue is full, notification about %s is dropped", wh.Name, service)
+		}
+	}
+}
+
+// deliver sends queued notifications one by one preserving their order
+func (h *hook) deliver() {
+	for n := range h.queue {
+		if err := h.sender.send(n); err != nil {
+			log.Errorf("Webhook %s failed: %v", h.sender.wh.Name, err)
+
+			continue
+		}
+		log.Debugf("Webhook %s notified about %s is %s", h.sender.wh.Name, n.Service, n.Status)
+	}
+}
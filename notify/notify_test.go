@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/internal/testutil"
+	"github.com/reportportal/service-index/probe"
+)
+
+func health(status string) map[string]interface{} {
+	return map[string]interface{}{"api": map[string]interface{}{"status": status, "error": "connection refused"}}
+}
+
+// newWebhookServer starts webhook endpoint failing the first attempt and verifying signatures of the others
+func newWebhookServer(t *testing.T, secret string) (*httptest.Server, <-chan map[string]interface{}) {
+	t.Helper()
+	var attempts int32
+	delivered := make(chan map[string]interface{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first attempt fails to check retries
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(SignatureHeader), "sha256="+Sign([]byte(secret), body); got != want {
+			t.Errorf("signature = %v, want %v", got, want)
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("payload %s isn't a JSON: %v", body, err)
+		}
+		delivered <- payload
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, delivered
+}
+
+func TestNotifier(t *testing.T) {
+	secretFile := testutil.WriteSecret(t, "secret", "s3cr3t")
+	srv, delivered := newWebhookServer(t, "s3cr3t")
+
+	wh := &Webhook{Name: "slack", URL: srv.URL, Preset: PresetSlack, SecretFile: secretFile, Confirmations: 2}
+	if err := wh.defaults(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := New([]*Webhook{wh}, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	now := time.Now()
+	for _, status := range []string{
+		aggregator.StatusUp,
+		// a single failed check is suppressed
+		aggregator.StatusDown,
+		aggregator.StatusUp,
+		// changes are suppressed during maintenance
+		aggregator.StatusMaintenance,
+		aggregator.StatusMaintenance,
+		aggregator.StatusDown,
+		aggregator.StatusDown,
+	} {
+		n.ObserveCheck(health(status), now)
+	}
+
+	select {
+	case payload := <-delivered:
+		if payload["text"] != "ReportPortal service api is DOWN (was UP)" {
+			t.Errorf("payload = %v", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification wasn't delivered")
+	}
+	select {
+	case payload := <-delivered:
+		t.Errorf("unexpected notification %v", payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSender_render(t *testing.T) {
+	for preset := range presets {
+		t.Run(preset, func(t *testing.T) {
+			s, err := newSender(&Webhook{Name: preset, Preset: preset}, http.DefaultClient)
+			if err != nil {
+				t.Fatalf("newSender() error = %v", err)
+			}
+			n := &Notification{Service: "api", Status: aggregator.StatusDown, Previous: aggregator.StatusUp, Entry: health("DOWN")}
+			if _, err := s.render(n); err != nil {
+				t.Errorf("render() error = %v", err)
+			}
+		})
+	}
+
+	s, err := newSender(&Webhook{Name: "broken", Template: `{"text": {{.Service}}}`}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.render(&Notification{Service: "api"}); err == nil {
+		t.Error("render() expected error for invalid JSON")
+	}
+}
+
+func TestWebhook_defaults(t *testing.T) {
+	zero, negative := 0, -1
+	tests := []struct {
+		name    string
+		retries *int
+		want    int
+		wantErr bool
+	}{
+		{name: "default", want: defaultRetries},
+		{name: "disabled", retries: &zero, want: 0},
+		{name: "negative", retries: &negative, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wh := &Webhook{URL: "http://localhost", Retries: tt.retries}
+			if err := wh.defaults(); (err != nil) != tt.wantErr {
+				t.Fatalf("defaults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && wh.retries() != tt.want {
+				t.Errorf("retries() = %d, want %d", wh.retries(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSender_sharedClient(t *testing.T) {
+	shared := &http.Client{Timeout: time.Minute}
+	s, err := newSender(&Webhook{Name: "generic", Preset: PresetGeneric, Timeout: probe.Duration(time.Second)}, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared.Timeout != time.Minute {
+		t.Errorf("timeout of the shared client changed to %v", shared.Timeout)
+	}
+	if got := s.r.GetClient().Timeout; got != time.Second {
+		t.Errorf("sender timeout = %v, want %v", got, time.Second)
+	}
+}
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+// SignatureHeader carries hex-encoded HMAC-SHA256 of the payload
+const SignatureHeader = "X-Signature-256"
+
+var (
+	errNoURL           = errors.New("url is required")
+	errInvalidPayload  = errors.New("rendered payload isn't a valid JSON")
+	errNegativeRetries = errors.New("retries must not be negative")
+)
+
+// presets are payload templates compatible with popular chat webhooks
+var presets = map[string]string{
+	PresetGeneric: `{"service": {{json .Service}}, "status": {{json .Status}}, "previous": {{json .Previous}}, ` +
+		`"time": {{json .Time}}, "entry": {{json .Entry}}}`,
+	PresetSlack:      `{"text": {{json .Message}}}`,
+	PresetMattermost: `{"text": {{json .Message}}, "username": "ReportPortal"}`,
+	PresetTeams: `{"@type": "MessageCard", "@context": "https://schema.org/extensions", ` +
+		`"themeColor": {{json (color .Status)}}, "summary": {{json .Message}}, "title": {{json .Message}}, ` +
+		`"text": {{json .Error}}}`,
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+
+		return string(b), err
+	},
+	"color": func(status string) string {
+		switch status {
+		case aggregator.StatusUp:
+			return "2EB886"
+		case aggregator.StatusDown:
+			return "D50200"
+		default:
+			return "F2C744"
+		}
+	},
+}
+
+// Notification describes status change of a service, it's a data of payload templates
+type Notification struct {
+	Service  string                 `json:"service"`
+	Status   string                 `json:"status"`
+	Previous string                 `json:"previous"`
+	Time     time.Time              `json:"time"`
+	Entry    map[string]interface{} `json:"entry,omitempty"`
+}
+
+// Message is a human-readable description of the change
+func (n *Notification) Message() string {
+	return fmt.Sprintf("ReportPortal service %s is %s (was %s)", n.Service, n.Status, n.Previous)
+}
+
+// Error is an error reported by the health entry, if any
+func (n *Notification) Error() string {
+	if e, ok := n.Entry["error"].(string); ok {
+		return e
+	}
+
+	return ""
+}
+
+// sender renders, signs and delivers notifications of a single webhook
+type sender struct {
+	wh     *Webhook
+	tmpl   *template.Template
+	secret []byte
+	r      *resty.Client
+}
+
+func newSender(wh *Webhook, httpClient *http.Client) (*sender, error) {
+	text := wh.Template
+	if text == "" {
+		text = presets[wh.Preset]
+	}
+	tmpl, err := template.New(wh.Name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect template of webhook %s: %w", wh.Name, err)
+	}
+	secret, err := wh.secret()
+	if err != nil {
+		return nil, err
+	}
+
+	// the client is shared with other components, timeout of the webhook must not leak into it
+	c := *httpClient
+	c.Timeout = time.Duration(wh.Timeout)
+	r := resty.NewWithClient(&c).
+		SetRetryCount(wh.retries()).
+		SetRetryWaitTime(500*time.Millisecond).
+		SetRetryMaxWaitTime(10*time.Second).
+		AddRetryCondition(func(rs *resty.Response, err error) bool {
+			return err != nil || rs.StatusCode() == http.StatusTooManyRequests || rs.StatusCode() >= http.StatusInternalServerError
+		}).
+		SetHeaders(wh.Headers).
+		SetHeader("Content-Type", "application/json")
+
+	return &sender{wh: wh, tmpl: tmpl, secret: secret, r: r}, nil
+}
+
+// render produces JSON payload of the notification
+func (s *sender) render(n *Notification) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.tmpl.Execute(&buf, n); err != nil {
+		return nil, fmt.Errorf("unable to render payload: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, errInvalidPayload
+	}
+
+	return buf.Bytes(), nil
+}
+
+// send delivers the notification retrying failed attempts
+func (s *sender) send(n *Notification) error {
+	payload, err := s.render(n)
+	if err != nil {
+		return err
+	}
+
+	rq := s.r.R().SetBody(payload)
+	if len(s.secret) > 0 {
+		rq.SetHeader(SignatureHeader, "sha256="+Sign(s.secret, payload))
+	}
+	rs, err := rq.Post(s.wh.URL)
+	if err != nil {
+		return fmt.Errorf("unable to deliver notification: %w", err)
+	}
+	if rs.IsError() {
+		return fmt.Errorf("unable to deliver notification: %s", rs.Status())
+	}
+
+	return nil
+}
+
+// Sign computes hex-encoded HMAC-SHA256 of the payload
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,173 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// AuthBearer attaches static bearer token
+	AuthBearer = "bearer"
+	// AuthBasic attaches basic auth credentials
+	AuthBasic = "basic"
+	// AuthOAuth2 obtains token using OAuth2 client credentials grant
+	AuthOAuth2 = "oauth2"
+)
+
+var errUnknownAuth = errors.New("unknown auth type")
+
+// AuthConfig declares credentials attached to probe requests.
+// Secrets are read from files and re-read once the files change
+type AuthConfig struct {
+	// Type is one of bearer, basic or oauth2
+	Type string `json:"type"`
+	// TokenFile contains static bearer token
+	TokenFile string `json:"tokenFile,omitempty"`
+	// Username and PasswordFile are basic auth credentials
+	Username     string `json:"username,omitempty"`
+	PasswordFile string `json:"passwordFile,omitempty"`
+	// TokenURL, ClientID, ClientSecretFile and Scopes configure OAuth2 client credentials grant
+	TokenURL         string   `json:"tokenURL,omitempty"`
+	ClientID         string   `json:"clientID,omitempty"`
+	ClientSecretFile string   `json:"clientSecretFile,omitempty"`
+	Scopes           []string `json:"scopes,omitempty"`
+}
+
+// authenticator attaches credentials to probe requests
+type authenticator interface {
+	apply(rq *resty.Request) error
+}
+
+// newAuthenticator creates authenticator declared by the config
+func newAuthenticator(cfg *AuthConfig, httpClient *http.Client) (authenticator, error) {
+	switch cfg.Type {
+	case AuthBearer:
+		return &bearerAuth{token: &secretFile{name: cfg.TokenFile}}, nil
+	case AuthBasic:
+		return &basicAuth{username: cfg.Username, password: &secretFile{name: cfg.PasswordFile}}, nil
+	case AuthOAuth2:
+		return &oauth2Auth{cfg: cfg, httpClient: httpClient, secret: &secretFile{name: cfg.ClientSecretFile}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownAuth, cfg.Type)
+	}
+}
+
+type bearerAuth struct {
+	token *secretFile
+}
+
+func (a *bearerAuth) apply(rq *resty.Request) error {
+	token, _, err := a.token.get()
+	if err != nil {
+		return err
+	}
+	rq.SetAuthToken(token)
+
+	return nil
+}
+
+type basicAuth struct {
+	username string
+	password *secretFile
+}
+
+func (a *basicAuth) apply(rq *resty.Request) error {
+	password, _, err := a.password.get()
+	if err != nil {
+		return err
+	}
+	rq.SetBasicAuth(a.username, password)
+
+	return nil
+}
+
+// oauth2Auth obtains tokens from the authorization service and caches them until expiry
+type oauth2Auth struct {
+	cfg        *AuthConfig
+	httpClient *http.Client
+	secret     *secretFile
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+func (a *oauth2Auth) apply(rq *resty.Request) error {
+	token, err := a.obtain(rq.Context())
+	if err != nil {
+		return fmt.Errorf("unable to obtain OAuth2 token: %w", err)
+	}
+	rq.SetAuthToken(token.AccessToken)
+
+	return nil
+}
+
+// obtain returns cached token, a new one is requested within the probe context once it expires or client secret changes
+func (a *oauth2Auth) obtain(ctx context.Context) (*oauth2.Token, error) {
+	secret, changed, err := a.secret.get()
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if changed {
+		a.token = nil
+	}
+	if a.token.Valid() {
+		return a.token, nil
+	}
+
+	cc := &clientcredentials.Config{
+		ClientID:     a.cfg.ClientID,
+		ClientSecret: secret,
+		TokenURL:     a.cfg.TokenURL,
+		Scopes:       a.cfg.Scopes,
+	}
+	token, err := cc.Token(context.WithValue(ctx, oauth2.HTTPClient, a.httpClient))
+	if err != nil {
+		return nil, err
+	}
+	a.token = token
+
+	return token, nil
+}
+
+// secretFile reads secret value from the file and caches it until the file changes
+type secretFile struct {
+	name string
+
+	mu    sync.Mutex
+	ver   fileVersion
+	value string
+}
+
+// get returns secret value and whether it has changed since the previous call
+func (s *secretFile) get() (string, bool, error) {
+	ver, err := statFile(s.name)
+	if err != nil {
+		return "", false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ver == s.ver {
+		return s.value, false, nil
+	}
+
+	data, err := os.ReadFile(s.name)
+	if err != nil {
+		return "", false, fmt.Errorf("unable to read secret: %w", err)
+	}
+	s.value, s.ver = strings.TrimSpace(string(data)), ver
+
+	return s.value, true, nil
+}
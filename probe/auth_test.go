@@ -0,0 +1,126 @@
+package probe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/reportportal/service-index/internal/testutil"
+)
+
+func TestClient_Authenticate(t *testing.T) {
+	var tokenRequests int32
+	authSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		atomic.AddInt32(&tokenRequests, 1)
+		if id, secret, ok := rq.BasicAuth(); !ok || id != "service-index" || secret != "client-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"oauth-token","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer authSrv.Close()
+
+	c, err := NewClient(&Config{Services: map[string]*ServiceConfig{
+		"api": {Auth: &AuthConfig{Type: AuthBearer, TokenFile: testutil.WriteSecret(t, "token", "static-token")}},
+		"uat": {Auth: &AuthConfig{Type: AuthBasic, Username: "admin", PasswordFile: testutil.WriteSecret(t, "password", "pass")}},
+		"jobs": {Auth: &AuthConfig{
+			Type:             AuthOAuth2,
+			TokenURL:         authSrv.URL,
+			ClientID:         "service-index",
+			ClientSecretFile: testutil.WriteSecret(t, "secret", "client-secret"),
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	tests := []struct {
+		service string
+		want    string
+	}{
+		{service: "api", want: "Bearer static-token"},
+		{service: "uat", want: "Basic YWRtaW46cGFzcw=="},
+		{service: "jobs", want: "Bearer oauth-token"},
+		{service: "jobs", want: "Bearer oauth-token"},
+		{service: "ui", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, rq *http.Request) {
+				got = rq.Header.Get("Authorization")
+			}))
+			defer srv.Close()
+
+			rq := resty.NewWithClient(c.HTTP()).R()
+			if err := c.Authenticate(tt.service, rq); err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if _, err := rq.Get(srv.URL); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if n := atomic.LoadInt32(&tokenRequests); n != 1 {
+		t.Errorf("token requested %d times, want 1", n)
+	}
+}
+
+func TestClient_Authenticate_tokenTimeout(t *testing.T) {
+	hung := make(chan struct{})
+	authSrv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, rq *http.Request) {
+		select {
+		case <-hung:
+		case <-rq.Context().Done():
+		}
+	}))
+	defer authSrv.Close()
+	defer close(hung)
+
+	c, err := NewClient(&Config{Services: map[string]*ServiceConfig{
+		"jobs": {Auth: &AuthConfig{
+			Type:             AuthOAuth2,
+			TokenURL:         authSrv.URL,
+			ClientID:         "service-index",
+			ClientSecretFile: testutil.WriteSecret(t, "secret", "client-secret"),
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Authenticate("jobs", resty.NewWithClient(c.HTTP()).R().SetContext(ctx))
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Authenticate() expected error of the hung token endpoint")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Authenticate() isn't bounded by the probe context")
+	}
+}
+
+func TestNewClient_UnknownAuth(t *testing.T) {
+	_, err := NewClient(&Config{Services: map[string]*ServiceConfig{
+		"api": {Auth: &AuthConfig{Type: "digest"}},
+	}})
+	if err == nil {
+		t.Error("NewClient() expected error for unknown auth type")
+	}
+}
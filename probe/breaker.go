@@ -0,0 +1,109 @@
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// ErrBreakerOpen is returned when probe is short-circuited by an open breaker
+var ErrBreakerOpen = errors.New("circuit breaker is open")
+
+// BreakerConfig holds circuit breaker settings
+type BreakerConfig struct {
+	// Threshold is a number of consecutive failures opening the breaker, zero disables breakers
+	Threshold int `env:"PROBE_BREAKER_THRESHOLD" envDefault:"5"`
+	// Cooldown is a duration the breaker stays open before a trial probe is let through
+	Cooldown time.Duration `env:"PROBE_BREAKER_COOLDOWN" envDefault:"30s"`
+}
+
+// BreakerState represents state of a service circuit breaker
+type BreakerState struct {
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	LastError string     `json:"lastError,omitempty"`
+	RetryAt   *time.Time `json:"retryAt,omitempty"`
+}
+
+// breaker is a per-service circuit breaker short-circuiting probes of services that keep failing
+type breaker struct {
+	cfg *BreakerConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	lastError string
+	openedAt  time.Time
+	trial     bool
+}
+
+func newBreaker(cfg *BreakerConfig) *breaker {
+	return &breaker{cfg: cfg, now: time.Now}
+}
+
+// allow checks whether probe may be performed.
+// Once cooldown of an open breaker passes, a single trial probe is let through
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.cfg.Threshold {
+		return nil
+	}
+	if !b.trial && b.now().Sub(b.openedAt) >= b.cfg.Cooldown {
+		b.trial = true
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrBreakerOpen, b.lastError)
+}
+
+// record registers probe result
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if err == nil {
+		b.failures = 0
+		b.lastError = ""
+
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	if b.failures >= b.cfg.Threshold {
+		b.openedAt = b.now()
+	}
+}
+
+// state returns current breaker state
+func (b *breaker) state() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := BreakerState{State: BreakerClosed, Failures: b.failures, LastError: b.lastError}
+	if b.failures < b.cfg.Threshold {
+		return st
+	}
+
+	retryAt := b.openedAt.Add(b.cfg.Cooldown)
+	st.RetryAt = &retryAt
+	if b.trial || !b.now().Before(retryAt) {
+		st.State = BreakerHalfOpen
+	} else {
+		st.State = BreakerOpen
+	}
+
+	return st
+}
@@ -0,0 +1,42 @@
+package probe
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_breaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBreaker(&BreakerConfig{Threshold: 2, Cooldown: time.Minute})
+	b.now = func() time.Time { return now }
+	errTimeout := errors.New("i/o timeout")
+
+	b.record(errTimeout)
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() after single failure error = %v", err)
+	}
+	b.record(errTimeout)
+	if err := b.allow(); !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("allow() after threshold error = %v, want %v", err, ErrBreakerOpen)
+	}
+	if st := b.state(); st.State != BreakerOpen || st.LastError != errTimeout.Error() {
+		t.Errorf("state() = %+v", st)
+	}
+
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow() trial probe error = %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("allow() concurrent trial error = %v, want %v", err, ErrBreakerOpen)
+	}
+	if st := b.state(); st.State != BreakerHalfOpen {
+		t.Errorf("state() = %v, want %v", st.State, BreakerHalfOpen)
+	}
+
+	b.record(nil)
+	if st := b.state(); st.State != BreakerClosed || st.Failures != 0 {
+		t.Errorf("state() after success = %+v", st)
+	}
+}
@@ -0,0 +1,321 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+// Client holds everything needed to probe info and health endpoints of services
+type Client struct {
+	cfg *Config
+	tls *tlsSource
+	// http is a client of third-party endpoints, it uses neither probe TLS settings nor the target policy
+	http *http.Client
+	// api is a client of configured infrastructure endpoints using probe TLS settings
+	api *http.Client
+	// probe is a client of discovered targets using probe TLS settings restricted by the target policy
+	probe  *http.Client
+	policy *policy
+	auth   map[string]authenticator
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+	grpc     map[string]*grpcConn
+}
+
+// NewClient creates probe client configured with TLS settings and per-service credentials
+func NewClient(cfg *Config) (*Client, error) {
+	tlsSrc, err := cfg.TLS.build()
+	if err != nil {
+		return nil, fmt.Errorf("unable to build probe TLS config: %w", err)
+	}
+	pol, err := cfg.Policy.build()
+	if err != nil {
+		return nil, fmt.Errorf("incorrect probe target policy: %w", err)
+	}
+	apiClient := newHTTPClient(cfg, tlsSrc)
+
+	auth := make(map[string]authenticator, len(cfg.Services))
+	for name, sc := range cfg.Services {
+		if sc == nil || sc.Auth == nil {
+			continue
+		}
+		a, err := newAuthenticator(sc.Auth, apiClient)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect auth config of service %s: %w", name, err)
+		}
+		auth[name] = a
+	}
+
+	return &Client{
+		cfg:      cfg,
+		tls:      tlsSrc,
+		http:     newHTTPClient(cfg, nil),
+		api:      apiClient,
+		probe:    newProbeHTTPClient(cfg, tlsSrc, pol),
+		policy:   pol,
+		auth:     auth,
+		breakers: map[string]*breaker{},
+		grpc:     map[string]*grpcConn{},
+	}, nil
+}
+
+// HTTP returns HTTP client of third-party endpoints such as webhooks and JWKS.
+// It uses default TLS settings rather than probe ones and isn't restricted by the target policy
+func (c *Client) HTTP() *http.Client {
+	return c.http
+}
+
+// CheckTarget checks the discovered node against the target policy, its endpoints must be either paths or URLs of the node itself
+func (c *Client) CheckTarget(base string, endpoints ...string) error {
+	b, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errTargetRejected, err)
+	}
+	for _, ep := range endpoints {
+		u, err := endpointURL(b, ep)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errTargetRejected, err)
+		}
+		if !strings.EqualFold(u.Hostname(), b.Hostname()) {
+			return fmt.Errorf("%w: endpoint %s points to another host", errTargetRejected, ep)
+		}
+	}
+
+	return c.policy.checkURL(b)
+}
+
+// endpointURL resolves request URL of the endpoint, it's either an absolute URL or a path starting with a slash
+func endpointURL(base *url.URL, ep string) (*url.URL, error) {
+	u, err := url.Parse(ep)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsAbs() {
+		return u, nil
+	}
+	if !strings.HasPrefix(ep, "/") || u.Host != "" {
+		return nil, fmt.Errorf("endpoint %s is neither a URL nor an absolute path", ep)
+	}
+
+	return base.JoinPath(u.Path), nil
+}
+
+// NewRestClient creates REST client of discovered targets retrying failed requests
+func (c *Client) NewRestClient() *resty.Client {
+	return c.newRestClient(c.probe)
+}
+
+// NewAPIRestClient creates REST client of configured endpoints such as discovery APIs retrying failed requests,
+// it isn't restricted by the target policy
+func (c *Client) NewAPIRestClient() *resty.Client {
+	return c.newRestClient(c.api)
+}
+
+func (c *Client) newRestClient(httpClient *http.Client) *resty.Client {
+	return resty.NewWithClient(httpClient).
+		SetRetryCount(c.cfg.Retry.Count).
+		SetRetryWaitTime(c.cfg.Retry.Wait).
+		SetRetryMaxWaitTime(c.cfg.Retry.MaxWait).
+		AddRetryCondition(func(rs *resty.Response, err error) bool {
+			if err != nil {
+				return true
+			}
+			code := rs.StatusCode()
+
+			return code == http.StatusBadGateway || code == http.StatusGatewayTimeout
+		}).
+		AddRetryHook(func(*resty.Response, error) {
+			retriesTotal.Inc()
+		})
+}
+
+// Do performs probe of the service guarded by its circuit breaker.
+// Returns error wrapping ErrBreakerOpen without calling the probe if the breaker is open
+func (c *Client) Do(service string, probe func() error) error {
+	b := c.breaker(service)
+	if b == nil {
+		return probe()
+	}
+
+	if err := b.allow(); err != nil {
+		shortCircuitedTotal.WithLabelValues(service).Inc()
+
+		return err
+	}
+	err := probe()
+	b.record(err)
+	breakerState.WithLabelValues(service).Set(breakerStateValues[b.state().State])
+
+	return err
+}
+
+// HealthResult builds health entry of the service from the probe result.
+// The service is marked DOWN with the failure reason if the probe failed
+func (c *Client) HealthResult(service string, rs map[string]interface{}, err error) map[string]interface{} {
+	if err != nil {
+		rs = map[string]interface{}{"status": aggregator.StatusDown, "error": err.Error()}
+	}
+	if st, ok := c.BreakerState(service); ok && st.State != BreakerClosed {
+		if rs == nil {
+			rs = map[string]interface{}{}
+		}
+		rs["circuitBreaker"] = st
+	}
+
+	return rs
+}
+
+// BreakerState returns state of the service circuit breaker, false if breakers are disabled
+func (c *Client) BreakerState(service string) (BreakerState, bool) {
+	b := c.breaker(service)
+	if b == nil {
+		return BreakerState{}, false
+	}
+
+	return b.state(), true
+}
+
+func (c *Client) breaker(service string) *breaker {
+	if c.cfg.Breaker.Threshold <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[service]
+	if !ok {
+		b = newBreaker(&c.cfg.Breaker)
+		c.breakers[service] = b
+	}
+
+	return b
+}
+
+// Concurrency returns limit of simultaneous probes of a single aggregation
+func (c *Client) Concurrency() int {
+	return c.cfg.Concurrency
+}
+
+// DiscoveryContext returns context limited by discovery timeout
+func (c *Client) DiscoveryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, c.cfg.Timeouts.Discovery)
+}
+
+// InfoContext returns context limited by info probe timeout of the service
+func (c *Client) InfoContext(ctx context.Context, service string) (context.Context, context.CancelFunc) {
+	if t := c.cfg.Service(service).InfoTimeout; t > 0 {
+		return withTimeout(ctx, time.Duration(t))
+	}
+
+	return withTimeout(ctx, c.cfg.Timeouts.Info)
+}
+
+// HealthContext returns context limited by health probe timeout of the service
+func (c *Client) HealthContext(ctx context.Context, service string) (context.Context, context.CancelFunc) {
+	if t := c.cfg.Service(service).HealthTimeout; t > 0 {
+		return withTimeout(ctx, time.Duration(t))
+	}
+
+	return withTimeout(ctx, c.cfg.Timeouts.Health)
+}
+
+// AggregationContext returns context limited by overall aggregation deadline
+func (c *Client) AggregationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, c.cfg.Timeouts.Aggregation)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// SchemeFor resolves scheme of the service probes
+func (c *Client) SchemeFor(service, declared string) string {
+	return c.cfg.SchemeFor(service, declared)
+}
+
+// Authenticate attaches credentials configured for the service to the request
+func (c *Client) Authenticate(service string, rq *resty.Request) error {
+	a, ok := c.auth[service]
+	if !ok {
+		return nil
+	}
+	if err := a.apply(rq); err != nil {
+		return fmt.Errorf("unable to authenticate probe of service %s: %w", service, err)
+	}
+
+	return nil
+}
+
+// newTransport creates transport of outgoing requests tuned by the transport settings
+func newTransport(cfg *Config) *http.Transport {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		transport = &http.Transport{}
+	}
+	transport = transport.Clone()
+	transport.MaxIdleConns = cfg.Transport.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.Transport.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = cfg.Transport.MaxConnsPerHost
+	transport.IdleConnTimeout = cfg.Transport.IdleConnTimeout
+
+	return transport
+}
+
+// newRoundTripper creates transport configured with TLS settings of the source, if any.
+// The transport is rebuilt once the CA bundle changes, customize is applied to every built transport
+func newRoundTripper(cfg *Config, tlsSrc *tlsSource, customize func(*http.Transport)) http.RoundTripper {
+	build := func(tlsCfg *tls.Config) *http.Transport {
+		transport := newTransport(cfg)
+		if tlsCfg != nil {
+			transport.TLSClientConfig = tlsCfg
+		}
+		if customize != nil {
+			customize(transport)
+		}
+
+		return transport
+	}
+	if tlsSrc == nil {
+		return build(nil)
+	}
+
+	return &tlsTransport{source: tlsSrc, build: build}
+}
+
+// newHTTPClient creates HTTP client configured with TLS settings of the source, if any.
+// The client has no overall timeout, requests are limited by their contexts instead
+func newHTTPClient(cfg *Config, tlsSrc *tlsSource) *http.Client {
+	return &http.Client{
+		Transport: newRoundTripper(cfg, tlsSrc, nil),
+	}
+}
+
+// newProbeHTTPClient creates HTTP client of discovered targets restricted by the target policy
+func newProbeHTTPClient(cfg *Config, tlsSrc *tlsSource, pol *policy) *http.Client {
+	transport := newRoundTripper(cfg, tlsSrc, func(t *http.Transport) {
+		t.DialContext = pol.dialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}, "")
+		// a proxy would dial the targets on its own, bypassing the policy
+		t.Proxy = nil
+	})
+
+	return &http.Client{
+		Transport:     &policyTransport{policy: pol, next: transport},
+		CheckRedirect: pol.checkRedirect,
+	}
+}
@@ -0,0 +1,161 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// SchemeHTTP is a plain HTTP scheme
+	SchemeHTTP = "http"
+	// SchemeHTTPS is a TLS-secured HTTP scheme
+	SchemeHTTPS = "https"
+)
+
+// Config holds settings of outgoing probes to info and health endpoints
+type Config struct {
+	// Scheme is a default scheme of probe requests
+	Scheme string `env:"PROBE_SCHEME" envDefault:"http"`
+	// File is an optional path to YAML or JSON file with per-service settings
+	File string `env:"PROBE_CONFIG_FILE" envDefault:""`
+	// Concurrency limits number of simultaneous probes of a single aggregation, zero means no limit
+	Concurrency int `env:"PROBE_CONCURRENCY" envDefault:"16"`
+	TLS         TLSConfig
+	Retry       RetryConfig
+	Breaker     BreakerConfig
+	Timeouts    TimeoutConfig
+	Transport   TransportConfig
+	Policy      PolicyConfig
+
+	// Services contains per-service settings loaded from the File
+	Services map[string]*ServiceConfig
+}
+
+// fileConfig represents content of the probe config file
+type fileConfig struct {
+	Services map[string]*ServiceConfig `json:"services,omitempty"`
+}
+
+// RetryConfig holds settings of probe retries.
+// Probes are idempotent GETs, so they are retried on transport errors with jittered exponential backoff
+type RetryConfig struct {
+	Count   int           `env:"PROBE_RETRY_COUNT"    envDefault:"2"`
+	Wait    time.Duration `env:"PROBE_RETRY_WAIT"     envDefault:"100ms"`
+	MaxWait time.Duration `env:"PROBE_RETRY_MAX_WAIT" envDefault:"1s"`
+}
+
+// TimeoutConfig holds timeouts of discovery and probe requests, zero means no timeout
+type TimeoutConfig struct {
+	Discovery time.Duration `env:"PROBE_DISCOVERY_TIMEOUT" envDefault:"5s"`
+	Info      time.Duration `env:"PROBE_INFO_TIMEOUT"      envDefault:"5s"`
+	Health    time.Duration `env:"PROBE_HEALTH_TIMEOUT"    envDefault:"5s"`
+	// Aggregation is an overall deadline of composite responses
+	Aggregation time.Duration `env:"AGGREGATION_TIMEOUT" envDefault:"10s"`
+}
+
+// TransportConfig tunes connection reuse of the shared probe HTTP client
+type TransportConfig struct {
+	MaxIdleConns        int           `env:"PROBE_MAX_IDLE_CONNS"          envDefault:"100"`
+	MaxIdleConnsPerHost int           `env:"PROBE_MAX_IDLE_CONNS_PER_HOST" envDefault:"4"`
+	MaxConnsPerHost     int           `env:"PROBE_MAX_CONNS_PER_HOST"      envDefault:"0"`
+	IdleConnTimeout     time.Duration `env:"PROBE_IDLE_CONN_TIMEOUT"       envDefault:"90s"`
+}
+
+// Duration is a time.Duration represented in config files as a string, e.g. 1.5s
+type Duration time.Duration
+
+// UnmarshalJSON parses duration from a string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("incorrect duration: %w", err)
+	}
+	*d = Duration(v)
+
+	return nil
+}
+
+// MarshalJSON represents duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ServiceConfig holds probe settings of a single service
+type ServiceConfig struct {
+	// Scheme overrides scheme of the probes, either http or https
+	Scheme string `json:"scheme,omitempty"`
+	// Auth declares credentials attached to the probes
+	Auth *AuthConfig `json:"auth,omitempty"`
+	// Type is a probe type, either http or grpc
+	Type string `json:"type,omitempty"`
+	// GRPCService is a service name sent in gRPC health check requests, empty means overall server health
+	GRPCService string `json:"grpcService,omitempty"`
+	// InfoTimeout and HealthTimeout override default probe timeouts
+	InfoTimeout   Duration `json:"infoTimeout,omitempty"`
+	HealthTimeout Duration `json:"healthTimeout,omitempty"`
+	// Validation declares rules health and info responses must satisfy
+	Validation *ValidationConfig `json:"validation,omitempty"`
+}
+
+// Load reads per-service settings from the configured file, if any
+func (c *Config) Load() error {
+	if c.File == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.File)
+	if err != nil {
+		return fmt.Errorf("unable to read probe config: %w", err)
+	}
+	var fc fileConfig
+	if err := yaml.UnmarshalStrict(data, &fc); err != nil {
+		return fmt.Errorf("unable to parse probe config: %w", err)
+	}
+	for name, sc := range fc.Services {
+		if sc == nil || sc.Validation == nil {
+			continue
+		}
+		if err := sc.Validation.Health.compile(); err != nil {
+			return fmt.Errorf("incorrect health validation of %s: %w", name, err)
+		}
+		if err := sc.Validation.Info.compile(); err != nil {
+			return fmt.Errorf("incorrect info validation of %s: %w", name, err)
+		}
+	}
+	c.Services = fc.Services
+
+	return nil
+}
+
+// Service returns settings of the service, never nil
+func (c *Config) Service(name string) *ServiceConfig {
+	if sc, ok := c.Services[name]; ok && sc != nil {
+		return sc
+	}
+
+	return &ServiceConfig{}
+}
+
+// SchemeFor resolves scheme of the service probes.
+// Per-service settings take precedence over the scheme declared by discovery, which takes precedence over the default one
+func (c *Config) SchemeFor(service, declared string) string {
+	if s := c.Service(service).Scheme; s != "" {
+		return s
+	}
+	if declared != "" {
+		return declared
+	}
+	if c.Scheme != "" {
+		return c.Scheme
+	}
+
+	return SchemeHTTP
+}
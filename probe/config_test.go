@@ -0,0 +1,64 @@
+package probe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig_Load(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "probes.yaml")
+	content := `
+services:
+  api:
+    scheme: https
+    healthTimeout: 1500ms
+  analyzer:
+    infoTimeout: 30s
+`
+	if err := os.WriteFile(f, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{File: f}
+	if err := c.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := c.Service("api"); got.Scheme != SchemeHTTPS || time.Duration(got.HealthTimeout) != 1500*time.Millisecond {
+		t.Errorf("Load() api = %+v", got)
+	}
+	if got := time.Duration(c.Service("analyzer").InfoTimeout); got != 30*time.Second {
+		t.Errorf("Load() analyzer info timeout = %v", got)
+	}
+
+	if err := os.WriteFile(f, []byte("services:\n  api:\n    unknown: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load(); err == nil {
+		t.Error("Load() expected error for unknown field")
+	}
+
+	if err := os.WriteFile(f, []byte("services:\n  api:\n    validation:\n      health:\n        expression: \"status ==\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Load(); err == nil {
+		t.Error("Load() expected error for incorrect expression")
+	}
+}
+
+func TestConfig_SchemeFor(t *testing.T) {
+	c := &Config{
+		Scheme:   SchemeHTTP,
+		Services: map[string]*ServiceConfig{"api": {Scheme: SchemeHTTPS}},
+	}
+	if got := c.SchemeFor("api", SchemeHTTP); got != SchemeHTTPS {
+		t.Errorf("SchemeFor(api) = %v, want %v", got, SchemeHTTPS)
+	}
+	if got := c.SchemeFor("uat", SchemeHTTPS); got != SchemeHTTPS {
+		t.Errorf("SchemeFor(uat) = %v, want %v", got, SchemeHTTPS)
+	}
+	if got := c.SchemeFor("ui", ""); got != SchemeHTTP {
+		t.Errorf("SchemeFor(ui) = %v, want %v", got, SchemeHTTP)
+	}
+}
@@ -0,0 +1,128 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+// Probe types
+const (
+	TypeHTTP = "http"
+	TypeGRPC = "grpc"
+)
+
+// TypeFor resolves probe type of the service.
+// Per-service settings take precedence over the type declared by discovery
+func (c *Client) TypeFor(service, declared string) string {
+	if t := c.cfg.Service(service).Type; t != "" {
+		return t
+	}
+	if declared != "" {
+		return declared
+	}
+
+	return TypeHTTP
+}
+
+// GRPCHealth checks health of the service at the target using grpc.health.v1 protocol.
+// SERVING status is reported as UP, NOT_SERVING as DOWN and the others as UNKNOWN
+func (c *Client) GRPCHealth(ctx context.Context, service, target string, useTLS bool) (map[string]interface{}, error) {
+	conn, err := c.grpcConn(target, useTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: c.cfg.Service(service).GRPCService,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gRPC health check failed: %w", err)
+	}
+
+	status := aggregator.StatusUnknown
+	switch rs.GetStatus() {
+	case healthpb.HealthCheckResponse_SERVING:
+		status = aggregator.StatusUp
+	case healthpb.HealthCheckResponse_NOT_SERVING:
+		status = aggregator.StatusDown
+	}
+
+	return map[string]interface{}{
+		"status":     status,
+		"grpcStatus": rs.GetStatus().String(),
+	}, nil
+}
+
+// grpcConn is a cached connection along with version of the CA bundle it trusts
+type grpcConn struct {
+	conn      *grpc.ClientConn
+	caVersion fileVersion
+}
+
+// grpcConn returns cached connection to the target, the connection is re-established once the CA bundle changes
+func (c *Client) grpcConn(target string, useTLS bool) (*grpc.ClientConn, error) {
+	key := fmt.Sprintf("%s|%t", target, useTLS)
+	creds, caVer, err := c.grpcCredentials(useTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.grpc[key]; ok {
+		if cached.caVersion == caVer {
+			return cached.conn, nil
+		}
+		_ = cached.conn.Close()
+		delete(c.grpc, key)
+	}
+
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect gRPC target: %w", err)
+	}
+	if err := c.policy.checkHost(host); err != nil {
+		return nil, err
+	}
+	// addresses are resolved by gRPC before dialing, so the host name is passed along
+	dial := c.policy.dialContext(&net.Dialer{}, host)
+	conn, err := grpc.NewClient(
+		target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dial(ctx, "tcp", addr)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create gRPC client: %w", err)
+	}
+	c.grpc[key] = &grpcConn{conn: conn, caVersion: caVer}
+
+	return conn, nil
+}
+
+// grpcCredentials returns transport credentials along with version of the CA bundle they trust
+func (c *Client) grpcCredentials(useTLS bool) (credentials.TransportCredentials, fileVersion, error) {
+	if !useTLS {
+		return insecure.NewCredentials(), fileVersion{}, nil
+	}
+	if c.tls == nil {
+		//nolint:gosec // MinVersion defaults to TLS 1.2 for clients
+		return credentials.NewTLS(&tls.Config{}), fileVersion{}, nil
+	}
+	tlsCfg, caVer, err := c.tls.config()
+	if err != nil {
+		return nil, fileVersion{}, err
+	}
+
+	return credentials.NewTLS(tlsCfg), caVer, nil
+}
@@ -0,0 +1,64 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+func TestClient_GRPCHealth(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := health.NewServer()
+	hs.SetServingStatus("analyzer", healthpb.HealthCheckResponse_NOT_SERVING)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	defer srv.Stop()
+
+	c, err := NewClient(&Config{Services: map[string]*ServiceConfig{
+		"analyzer": {Type: TypeGRPC, GRPCService: "analyzer"},
+		"unknown":  {Type: TypeGRPC, GRPCService: "unknown"},
+	}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	tests := []struct {
+		service string
+		want    string
+		wantErr bool
+	}{
+		{service: "metrics-gatherer", want: aggregator.StatusUp},
+		{service: "analyzer", want: aggregator.StatusDown},
+		{service: "unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			rs, err := c.GRPCHealth(context.Background(), tt.service, lis.Addr().String(), false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GRPCHealth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && rs["status"] != tt.want {
+				t.Errorf("GRPCHealth() status = %v, want %v", rs["status"], tt.want)
+			}
+		})
+	}
+
+	if got := c.TypeFor("analyzer", ""); got != TypeGRPC {
+		t.Errorf("TypeFor() = %v, want %v", got, TypeGRPC)
+	}
+	if got := c.TypeFor("api", ""); got != TypeHTTP {
+		t.Errorf("TypeFor() = %v, want %v", got, TypeHTTP)
+	}
+}
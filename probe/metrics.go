@@ -0,0 +1,34 @@
+package probe
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// breakerStateValues maps breaker states to gauge values
+var breakerStateValues = map[string]float64{
+	BreakerClosed:   0,
+	BreakerHalfOpen: 1,
+	BreakerOpen:     2,
+}
+
+var (
+	retriesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "service_index",
+		Subsystem: "probe",
+		Name:      "retries_total",
+		Help:      "Number of retried probe requests",
+	})
+	shortCircuitedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "service_index",
+		Subsystem: "probe",
+		Name:      "short_circuited_total",
+		Help:      "Number of probes short-circuited by an open circuit breaker",
+	}, []string{"service"})
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "service_index",
+		Subsystem: "probe",
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state per service: 0 - closed, 1 - half-open, 2 - open",
+	}, []string{"service"})
+)
@@ -0,0 +1,236 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+var (
+	errTargetRejected    = errors.New("target is rejected by policy")
+	errCrossHostRedirect = errors.New("redirect to another host")
+	errResponseTooLarge  = errors.New("response exceeds size limit")
+)
+
+// PolicyConfig restricts targets of probes so that discovered objects can't turn the index into a proxy.
+// Denied addresses and hosts are never probed. If any of allowed CIDRs or hosts is configured,
+// a target must match one of them
+type PolicyConfig struct {
+	AllowedCIDRs []string `env:"PROBE_ALLOWED_CIDRS" envDefault:""`
+	// AllowedHosts are host names, a leading wildcard matches subdomains, e.g. *.svc.cluster.local
+	AllowedHosts []string `env:"PROBE_ALLOWED_HOSTS" envDefault:""`
+	// DeniedCIDRs default to link-local ranges and addresses of cloud metadata services
+	DeniedCIDRs []string `env:"PROBE_DENIED_CIDRS" envDefault:"169.254.0.0/16,fe80::/10,fd00:ec2::254/128,100.100.100.200/32"`
+	DeniedHosts []string `env:"PROBE_DENIED_HOSTS" envDefault:"metadata,metadata.google.internal,metadata.goog"`
+	// MaxResponseSize limits body of probe responses in bytes, zero means no limit
+	MaxResponseSize int64 `env:"PROBE_MAX_RESPONSE_SIZE" envDefault:"1048576"`
+}
+
+// policy is a compiled PolicyConfig
+type policy struct {
+	allowedNets  []*net.IPNet
+	deniedNets   []*net.IPNet
+	allowedHosts []string
+	deniedHosts  []string
+	maxSize      int64
+}
+
+func (c *PolicyConfig) build() (*policy, error) {
+	p := &policy{allowedHosts: lower(c.AllowedHosts), deniedHosts: lower(c.DeniedHosts), maxSize: c.MaxResponseSize}
+	var err error
+	if p.allowedNets, err = parseCIDRs(c.AllowedCIDRs); err != nil {
+		return nil, err
+	}
+	if p.deniedNets, err = parseCIDRs(c.DeniedCIDRs); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func parseCIDRs(ss []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(ss))
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect CIDR %q: %w", s, err)
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+func lower(ss []string) []string {
+	res := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
+
+// restricted reports whether targets must match allowed CIDRs or hosts
+func (p *policy) restricted() bool {
+	return len(p.allowedNets) > 0 || len(p.allowedHosts) > 0
+}
+
+// checkURL checks target of the request. Host names not allowed explicitly are checked once resolved on dial
+func (p *policy) checkURL(u *url.URL) error {
+	if u.Scheme != SchemeHTTP && u.Scheme != SchemeHTTPS {
+		return fmt.Errorf("%w: unsupported scheme %q", errTargetRejected, u.Scheme)
+	}
+
+	return p.checkHost(u.Hostname())
+}
+
+func (p *policy) checkHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(ip, false)
+	}
+	if matchHost(p.deniedHosts, host) {
+		return fmt.Errorf("%w: host %s is denied", errTargetRejected, host)
+	}
+	if p.restricted() && len(p.allowedNets) == 0 && !matchHost(p.allowedHosts, host) {
+		return fmt.Errorf("%w: host %s isn't allowed", errTargetRejected, host)
+	}
+
+	return nil
+}
+
+// checkIP checks address of the target, allowedByName means host name of the target is allowed explicitly
+func (p *policy) checkIP(ip net.IP, allowedByName bool) error {
+	if matchNet(p.deniedNets, ip) {
+		return fmt.Errorf("%w: address %s is denied", errTargetRejected, ip)
+	}
+	if p.restricted() && !allowedByName && !matchNet(p.allowedNets, ip) {
+		return fmt.Errorf("%w: address %s isn't allowed", errTargetRejected, ip)
+	}
+
+	return nil
+}
+
+func matchNet(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchHost(patterns []string, host string) bool {
+	for _, p := range patterns {
+		if suffix, ok := strings.CutPrefix(p, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dialContext checks resolved addresses of targets before connecting to them.
+// Host is a name of the target if the dialed address is resolved already, empty means host of the address
+func (p *policy) dialContext(dialer *net.Dialer, host string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		name := host
+		if name == "" {
+			var err error
+			if name, _, err = net.SplitHostPort(addr); err != nil {
+				return nil, err
+			}
+		}
+		allowedByName := net.ParseIP(name) == nil && matchHost(p.allowedHosts, strings.ToLower(name))
+
+		d := *dialer
+		d.Control = func(_, address string, _ syscall.RawConn) error {
+			ipS, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(ipS)
+			if ip == nil {
+				return fmt.Errorf("%w: unresolved address %s", errTargetRejected, address)
+			}
+
+			return p.checkIP(ip, allowedByName)
+		}
+
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// checkRedirect allows redirects within the host of the original request only
+func (p *policy) checkRedirect(rq *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !strings.EqualFold(rq.URL.Host, via[0].URL.Host) {
+		return fmt.Errorf("%w: %s", errCrossHostRedirect, rq.URL.Host)
+	}
+
+	return p.checkURL(rq.URL)
+}
+
+// policyTransport checks targets of requests and limits size of responses
+type policyTransport struct {
+	policy *policy
+	next   http.RoundTripper
+}
+
+func (t *policyTransport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	if err := t.policy.checkURL(rq.URL); err != nil {
+		return nil, err
+	}
+	rs, err := t.next.RoundTrip(rq)
+	if err != nil || t.policy.maxSize <= 0 {
+		return rs, err
+	}
+	if rs.ContentLength > t.policy.maxSize {
+		rs.Body.Close()
+
+		return nil, fmt.Errorf("%w: %d bytes", errResponseTooLarge, rs.ContentLength)
+	}
+	rs.Body = &limitedBody{ReadCloser: rs.Body, left: t.policy.maxSize}
+
+	return rs, nil
+}
+
+// limitedBody fails reading once the limit is exceeded rather than truncating the response silently
+type limitedBody struct {
+	io.ReadCloser
+	left int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		return 0, errResponseTooLarge
+	}
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	if b.left < 0 {
+		return n, errResponseTooLarge
+	}
+
+	return n, err
+}
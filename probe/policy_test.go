@@ -0,0 +1,138 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_checkURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     PolicyConfig
+		url     string
+		wantErr bool
+	}{
+		{name: "default", url: "http://api.rp.svc.cluster.local:8585/info"},
+		{name: "metadata address", cfg: PolicyConfig{DeniedCIDRs: []string{"169.254.0.0/16"}}, url: "http://169.254.169.254/latest", wantErr: true},
+		{name: "metadata host", cfg: PolicyConfig{DeniedHosts: []string{"metadata.google.internal"}}, url: "http://Metadata.Google.Internal./", wantErr: true},
+		{name: "unsupported scheme", url: "file:///etc/passwd", wantErr: true},
+		{name: "allowed host", cfg: PolicyConfig{AllowedHosts: []string{"*.svc.cluster.local"}}, url: "http://api.rp.svc.cluster.local/info"},
+		{name: "not allowed host", cfg: PolicyConfig{AllowedHosts: []string{"*.svc.cluster.local"}}, url: "http://example.com/info", wantErr: true},
+		{name: "allowed address", cfg: PolicyConfig{AllowedCIDRs: []string{"10.0.0.0/8"}}, url: "http://10.1.2.3:8080/info"},
+		{name: "not allowed address", cfg: PolicyConfig{AllowedCIDRs: []string{"10.0.0.0/8"}}, url: "http://192.168.0.1/info", wantErr: true},
+		// names are checked on dial once resolved
+		{name: "name with allowed CIDRs", cfg: PolicyConfig{AllowedCIDRs: []string{"10.0.0.0/8"}}, url: "http://example.com/info"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.cfg.build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, _ := url.Parse(tt.url)
+			if err := p.checkURL(u); (err != nil) != tt.wantErr {
+				t.Errorf("checkURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_CheckTarget(t *testing.T) {
+	c, err := NewClient(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		endpoints []string
+		wantErr   bool
+	}{
+		{name: "paths and URLs of the node", endpoints: []string{"/info", "http://api.rp.svc:8585/health"}},
+		{name: "another host", endpoints: []string{"http://example.com/info"}, wantErr: true},
+		{name: "userinfo path", endpoints: []string{"@example.com/info"}, wantErr: true},
+		{name: "relative path", endpoints: []string{"info"}, wantErr: true},
+		{name: "network-path reference", endpoints: []string{"//example.com/info"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.CheckTarget("http://api.rp.svc", tt.endpoints...)
+			if tt.wantErr != errors.Is(err, errTargetRejected) {
+				t.Errorf("CheckTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Policy(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			// localhost and 127.0.0.1 are different hosts
+			http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 2048)))
+		case "/chunked":
+			w.(http.Flusher).Flush()
+			w.Write([]byte(strings.Repeat("x", 2048)))
+		default:
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(&Config{Policy: PolicyConfig{MaxResponseSize: 1024}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := c.NewRestClient().SetRetryCount(0)
+	if _, err := r.R().Get(srv.URL + "/info"); err != nil {
+		t.Errorf("Get(/info) error = %v", err)
+	}
+	if _, err := r.R().Get(srv.URL + "/redirect"); !errors.Is(err, errCrossHostRedirect) {
+		t.Errorf("Get(/redirect) error = %v, want cross-host redirect", err)
+	}
+	for _, path := range []string{"/large", "/chunked"} {
+		if _, err := r.R().Get(srv.URL + path); !errors.Is(err, errResponseTooLarge) {
+			t.Errorf("Get(%s) error = %v, want too large response", path, err)
+		}
+	}
+}
+
+func TestClient_Policy_deniedAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	denied, err := NewClient(&Config{Policy: PolicyConfig{AllowedHosts: []string{"localhost"}, DeniedCIDRs: []string{"127.0.0.0/8", "::1/128"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// allowed host name doesn't bypass denied addresses it resolves to
+	localURL := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	if _, err := denied.NewRestClient().SetRetryCount(0).R().SetContext(context.Background()).Get(localURL); !errors.Is(err, errTargetRejected) {
+		t.Errorf("Get(localhost) error = %v, want rejected address", err)
+	}
+}
+
+func TestClient_Policy_proxy(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://proxy.example.com:3128")
+	t.Setenv("HTTPS_PROXY", "http://proxy.example.com:3128")
+
+	c, err := NewClient(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, ok := c.probe.Transport.(*policyTransport)
+	if !ok {
+		t.Fatalf("probe transport = %T", c.probe.Transport)
+	}
+	if transport, ok := pt.next.(*http.Transport); !ok || transport.Proxy != nil {
+		t.Errorf("probe transport = %+v, want no proxy", pt.next)
+	}
+}
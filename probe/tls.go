@@ -0,0 +1,224 @@
+package probe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	errNoCertificates = errors.New("no certificates found in CA bundle")
+	errKeyPairPartial = errors.New("both client certificate and key must be provided")
+)
+
+// TLSConfig holds TLS settings of outgoing probes.
+// Certificate files are re-read once they are modified, so rotated certificates are picked up without restart
+type TLSConfig struct {
+	// CAFile is a PEM bundle of CAs trusted in addition to the system ones
+	CAFile string `env:"PROBE_TLS_CA_FILE"`
+	// CertFile and KeyFile is a client key pair used for mutual TLS
+	CertFile string `env:"PROBE_TLS_CERT_FILE"`
+	KeyFile  string `env:"PROBE_TLS_KEY_FILE"`
+	// ServerName overrides server name used to verify certificates
+	ServerName string `env:"PROBE_TLS_SERVER_NAME"`
+}
+
+func (c *TLSConfig) customized() bool {
+	return c.CAFile != "" || c.CertFile != "" || c.KeyFile != "" || c.ServerName != ""
+}
+
+// build creates source of TLS client configs, returns nil if TLS isn't customized
+func (c *TLSConfig) build() (*tlsSource, error) {
+	if !c.customized() {
+		return nil, nil
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, errKeyPairPartial
+	}
+
+	s := &tlsSource{serverName: c.ServerName}
+	if c.CertFile != "" {
+		s.keyPair = &keyPairReloader{certFile: c.CertFile, keyFile: c.KeyFile}
+		if _, err := s.keyPair.get(); err != nil {
+			return nil, err
+		}
+	}
+	if c.CAFile != "" {
+		s.ca = &caReloader{file: c.CAFile}
+	}
+	if _, _, err := s.config(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// tlsSource provides TLS client config of probes trusting the latest CA bundle.
+// Certificates are verified by crypto/tls as usual, against the dialed host unless the server name is overridden
+type tlsSource struct {
+	serverName string
+	ca         *caReloader
+	keyPair    *keyPairReloader
+
+	mu      sync.Mutex
+	current *tls.Config
+	version fileVersion
+}
+
+// config returns TLS config along with version of the CA bundle it trusts, the config is rebuilt once the bundle changes
+func (s *tlsSource) config() (*tls.Config, fileVersion, error) {
+	var roots *x509.CertPool
+	var ver fileVersion
+	if s.ca != nil {
+		var err error
+		if roots, ver, err = s.ca.get(); err != nil {
+			return nil, fileVersion{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil && ver == s.version {
+		return s.current, ver, nil
+	}
+
+	//nolint:gosec // MinVersion defaults to TLS 1.2 for clients
+	cfg := &tls.Config{ServerName: s.serverName, RootCAs: roots}
+	if s.keyPair != nil {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return s.keyPair.get()
+		}
+	}
+	s.current, s.version = cfg, ver
+
+	return cfg, ver, nil
+}
+
+// tlsTransport rebuilds the underlying transport once TLS config changes, so connections trust the rotated CA bundle
+type tlsTransport struct {
+	source *tlsSource
+	build  func(*tls.Config) *http.Transport
+
+	mu      sync.Mutex
+	current *http.Transport
+	version fileVersion
+}
+
+func (t *tlsTransport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	next, err := t.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	return next.RoundTrip(rq)
+}
+
+func (t *tlsTransport) transport() (*http.Transport, error) {
+	cfg, ver, err := t.source.config()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current == nil || ver != t.version {
+		if t.current != nil {
+			t.current.CloseIdleConnections()
+		}
+		t.current, t.version = t.build(cfg), ver
+	}
+
+	return t.current, nil
+}
+
+// fileVersion identifies file contents by modification time and size
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(name string) (fileVersion, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return fileVersion{}, fmt.Errorf("unable to stat %s: %w", name, err)
+	}
+
+	return fileVersion{modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+// keyPairReloader loads client key pair and reloads it once files change
+type keyPairReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certVer fileVersion
+	keyVer  fileVersion
+}
+
+func (r *keyPairReloader) get() (*tls.Certificate, error) {
+	certVer, err := statFile(r.certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyVer, err := statFile(r.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && certVer == r.certVer && keyVer == r.keyVer {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load client key pair: %w", err)
+	}
+	r.cert, r.certVer, r.keyVer = &cert, certVer, keyVer
+
+	return r.cert, nil
+}
+
+// caReloader loads CA bundle and reloads it once the file changes
+type caReloader struct {
+	file string
+
+	mu   sync.Mutex
+	pool *x509.CertPool
+	ver  fileVersion
+}
+
+func (r *caReloader) get() (*x509.CertPool, fileVersion, error) {
+	ver, err := statFile(r.file)
+	if err != nil {
+		return nil, fileVersion{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pool != nil && ver == r.ver {
+		return r.pool, r.ver, nil
+	}
+
+	pem, err := os.ReadFile(r.file)
+	if err != nil {
+		return nil, fileVersion{}, fmt.Errorf("unable to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fileVersion{}, errNoCertificates
+	}
+	r.pool, r.ver = pool, ver
+
+	return r.pool, r.ver, nil
+}
@@ -0,0 +1,189 @@
+package probe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTLSServer starts server presenting self-signed certificate valid for the addresses only
+func newTLSServer(t *testing.T, ips ...net.IP) (srv *httptest.Server, caPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "probe test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func writeCA(t *testing.T, file string, caPEM []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(file, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func get(c *http.Client, url string) error {
+	rs, err := c.Get(url)
+	if err == nil {
+		rs.Body.Close()
+	}
+
+	return err
+}
+
+func TestNewClient_CABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeCA(t, caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), time.Now())
+
+	tests := []struct {
+		name    string
+		tls     TLSConfig
+		wantErr bool
+	}{
+		{name: "system roots only", tls: TLSConfig{}, wantErr: true},
+		{name: "CA bundle", tls: TLSConfig{CAFile: caFile}},
+		{name: "server name override", tls: TLSConfig{CAFile: caFile, ServerName: "example.com"}},
+		{name: "wrong server name", tls: TLSConfig{CAFile: caFile, ServerName: "reportportal.io"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(&Config{TLS: tt.tls})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			for name, hc := range map[string]*http.Client{"api": c.api, "probe": c.probe} {
+				if err := get(hc, srv.URL); (err != nil) != tt.wantErr {
+					t.Errorf("%s Get() error = %v, wantErr %v", name, err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestNewClient_CABundle_IPAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		ip      net.IP
+		wantErr bool
+	}{
+		{name: "certificate of the target", ip: net.ParseIP("127.0.0.1")},
+		{name: "certificate of another address", ip: net.ParseIP("192.0.2.10"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, caPEM := newTLSServer(t, tt.ip)
+			caFile := filepath.Join(t.TempDir(), "ca.pem")
+			writeCA(t, caFile, caPEM, time.Now())
+
+			c, err := NewClient(&Config{TLS: TLSConfig{CAFile: caFile}})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if err := get(c.probe, srv.URL); (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewClient_CARotation(t *testing.T) {
+	localhost := net.ParseIP("127.0.0.1")
+	oldSrv, oldCA := newTLSServer(t, localhost)
+	newSrv, newCA := newTLSServer(t, localhost)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeCA(t, caFile, oldCA, time.Now().Add(-time.Minute))
+
+	c, err := NewClient(&Config{TLS: TLSConfig{CAFile: caFile}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := get(c.probe, oldSrv.URL); err != nil {
+		t.Fatalf("Get() error = %v before rotation", err)
+	}
+	if err := get(c.probe, newSrv.URL); err == nil {
+		t.Fatal("Get() error = nil, want untrusted certificate before rotation")
+	}
+
+	writeCA(t, caFile, newCA, time.Now())
+	if err := get(c.probe, newSrv.URL); err != nil {
+		t.Errorf("Get() error = %v after rotation", err)
+	}
+}
+
+func TestClient_HTTP_DefaultTLS(t *testing.T) {
+	srv, caPEM := newTLSServer(t, net.ParseIP("127.0.0.1"))
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeCA(t, caFile, caPEM, time.Now())
+
+	c, err := NewClient(&Config{TLS: TLSConfig{CAFile: caFile, ServerName: "reportportal-api"}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	transport, ok := c.HTTP().Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("HTTP() transport = %T", c.HTTP().Transport)
+	}
+	if cfg := transport.TLSClientConfig; cfg != nil && (cfg.ServerName != "" || cfg.RootCAs != nil || cfg.GetClientCertificate != nil) {
+		t.Errorf("HTTP() uses probe TLS settings: %+v", cfg)
+	}
+	// the probe CA isn't trusted by the client of third-party endpoints
+	if err := get(c.HTTP(), srv.URL); err == nil {
+		t.Error("HTTP() Get() error = nil, want untrusted certificate")
+	}
+}
+
+func TestTLSConfig_build(t *testing.T) {
+	if src, err := (&TLSConfig{}).build(); src != nil || err != nil {
+		t.Errorf("build() = %v, %v, want nil for default TLS", src, err)
+	}
+	if _, err := (&TLSConfig{CertFile: "client.pem"}).build(); err != errKeyPairPartial {
+		t.Errorf("build() error = %v, want %v", err, errKeyPairPartial)
+	}
+	if _, err := (&TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}).build(); err == nil {
+		t.Error("build() expected error for missing CA bundle")
+	}
+}
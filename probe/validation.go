@@ -0,0 +1,226 @@
+package probe
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/jmespath/go-jmespath"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+// Validation rules
+const (
+	RuleStatusCode = "statusCode"
+	RuleExpression = "expression"
+	RuleMinVersion = "minVersion"
+	RuleMaxLatency = "maxLatency"
+)
+
+// ValidationConfig declares rules validating responses of the service probes
+type ValidationConfig struct {
+	Health *Rules `json:"health,omitempty"`
+	Info   *Rules `json:"info,omitempty"`
+}
+
+// Rules is a set of response validation rules, empty rules are skipped
+type Rules struct {
+	// ExpectedStatus lists accepted HTTP status codes
+	ExpectedStatus []int `json:"expectedStatus,omitempty"`
+	// Expression is a JMESPath expression evaluated against the response body, it must produce a truthy value
+	Expression string `json:"expression,omitempty"`
+	// MinVersion is a minimum accepted build.version of the response body
+	MinVersion string `json:"minVersion,omitempty"`
+	// MaxLatency is a maximum accepted response time
+	MaxLatency Duration `json:"maxLatency,omitempty"`
+
+	expr *jmespath.JMESPath
+}
+
+// Violation describes a failed validation rule
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// compile prepares rules for evaluation and checks they are correct
+func (r *Rules) compile() error {
+	if r == nil {
+		return nil
+	}
+	if r.Expression != "" {
+		expr, err := jmespath.Compile(r.Expression)
+		if err != nil {
+			return fmt.Errorf("incorrect expression %q: %w", r.Expression, err)
+		}
+		r.expr = expr
+	}
+	if r.MinVersion != "" {
+		if _, err := ParseVersion(r.MinVersion); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the response against the rules.
+// Status code is not checked if it is zero
+func (r *Rules) Validate(code int, latency time.Duration, body map[string]interface{}) []Violation {
+	if r == nil {
+		return nil
+	}
+
+	checks := []Violation{
+		{Rule: RuleStatusCode, Message: r.checkStatus(code)},
+		{Rule: RuleExpression, Message: r.evaluate(body)},
+		{Rule: RuleMinVersion, Message: r.checkVersion(body)},
+		{Rule: RuleMaxLatency, Message: r.checkLatency(latency)},
+	}
+	var violations []Violation
+	for _, c := range checks {
+		if c.Message != "" {
+			violations = append(violations, c)
+		}
+	}
+
+	return violations
+}
+
+// checkStatus returns description of violated status code rule, empty if it passes or isn't declared
+func (r *Rules) checkStatus(code int) string {
+	if code == 0 || len(r.ExpectedStatus) == 0 || slices.Contains(r.ExpectedStatus, code) {
+		return ""
+	}
+
+	return fmt.Sprintf("status code %d isn't one of %v", code, r.ExpectedStatus)
+}
+
+// checkLatency returns description of violated latency rule, empty if it passes or isn't declared
+func (r *Rules) checkLatency(latency time.Duration) string {
+	if r.MaxLatency <= 0 || latency <= time.Duration(r.MaxLatency) {
+		return ""
+	}
+
+	return fmt.Sprintf("latency %s exceeds %s", latency, time.Duration(r.MaxLatency))
+}
+
+func (r *Rules) evaluate(body map[string]interface{}) string {
+	if r.Expression == "" {
+		return ""
+	}
+	expr := r.expr
+	if expr == nil {
+		var err error
+		if expr, err = jmespath.Compile(r.Expression); err != nil {
+			return fmt.Sprintf("incorrect expression: %v", err)
+		}
+	}
+
+	// JMESPath evaluates against generic JSON values, typed nil map isn't treated as null
+	var data interface{}
+	if body != nil {
+		data = body
+	}
+	rs, err := expr.Search(data)
+	if err != nil {
+		return fmt.Sprintf("unable to evaluate %q: %v", r.Expression, err)
+	}
+	if !truthy(rs) {
+		return fmt.Sprintf("%q evaluated to %v", r.Expression, rs)
+	}
+
+	return ""
+}
+
+func (r *Rules) checkVersion(body map[string]interface{}) string {
+	if r.MinVersion == "" {
+		return ""
+	}
+	build, _ := body["build"].(map[string]interface{})
+	declared, _ := build["version"].(string)
+	if declared == "" {
+		return "build.version is missing"
+	}
+
+	cmp, err := CompareVersions(declared, r.MinVersion)
+	if err != nil {
+		return err.Error()
+	}
+	if cmp < 0 {
+		return fmt.Sprintf("version %s is lower than %s", declared, r.MinVersion)
+	}
+
+	return ""
+}
+
+// truthy follows JMESPath notion of truth: false, null and empty values are false
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	default:
+		return true
+	}
+}
+
+// ValidateHealth validates health response of the service.
+// Violations are added to the result, which is reported DOWN if any rule fails
+func (c *Client) ValidateHealth(service string, rs *resty.Response, body map[string]interface{}) map[string]interface{} {
+	violations := c.validate(c.cfg.Service(service).validation().Health, rs, body)
+	if len(violations) == 0 {
+		return body
+	}
+
+	res := make(map[string]interface{}, len(body)+2)
+	for k, v := range body {
+		res[k] = v
+	}
+	res["status"] = aggregator.StatusDown
+	res["violations"] = violations
+
+	return res
+}
+
+// ValidateInfo validates info response of the service, violations are added to the result
+func (c *Client) ValidateInfo(service string, rs *resty.Response, body map[string]interface{}) map[string]interface{} {
+	violations := c.validate(c.cfg.Service(service).validation().Info, rs, body)
+	if len(violations) == 0 {
+		return body
+	}
+
+	res := make(map[string]interface{}, len(body)+1)
+	for k, v := range body {
+		res[k] = v
+	}
+	res["violations"] = violations
+
+	return res
+}
+
+func (c *Client) validate(r *Rules, rs *resty.Response, body map[string]interface{}) []Violation {
+	if r == nil || rs == nil {
+		return nil
+	}
+
+	return r.Validate(rs.StatusCode(), rs.Time(), body)
+}
+
+// validation returns validation rules of the service, never nil
+func (sc *ServiceConfig) validation() *ValidationConfig {
+	if sc.Validation == nil {
+		return &ValidationConfig{}
+	}
+
+	return sc.Validation
+}
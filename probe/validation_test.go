@@ -0,0 +1,92 @@
+package probe
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRules_Validate(t *testing.T) {
+	rules := &Rules{
+		ExpectedStatus: []int{200},
+		Expression:     "status == 'UP'",
+		MinVersion:     "5.11.0",
+		MaxLatency:     Duration(time.Second),
+	}
+	if err := rules.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		code    int
+		latency time.Duration
+		body    map[string]interface{}
+		want    []string
+	}{
+		{
+			name:    "valid",
+			code:    200,
+			latency: 10 * time.Millisecond,
+			body:    map[string]interface{}{"status": "UP", "build": map[string]interface{}{"version": "5.12.1"}},
+		},
+		{
+			name:    "error status with body",
+			code:    500,
+			latency: 10 * time.Millisecond,
+			body:    map[string]interface{}{"status": "DOWN", "build": map[string]interface{}{"version": "5.11.0"}},
+			want:    []string{RuleStatusCode, RuleExpression},
+		},
+		{
+			name:    "outdated and slow",
+			code:    200,
+			latency: 2 * time.Second,
+			body:    map[string]interface{}{"status": "UP", "build": map[string]interface{}{"version": "5.10.3-SNAPSHOT"}},
+			want:    []string{RuleMinVersion, RuleMaxLatency},
+		},
+		{
+			name: "empty body",
+			code: 200,
+			want: []string{RuleExpression, RuleMinVersion},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules.Validate(tt.code, tt.latency, tt.body)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Validate() = %+v, want rules %v", got, tt.want)
+			}
+			for i, v := range got {
+				if v.Rule != tt.want[i] {
+					t.Errorf("Validate()[%d] = %+v, want rule %v", i, v, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "5.11.0", b: "5.11", want: 0},
+		{a: "5.9.2", b: "5.11.0", want: -1},
+		{a: "v24.1.0", b: "5.11.0", want: 1},
+		{a: "5.11.0-SNAPSHOT", b: "5.11.0", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			got, err := CompareVersions(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("CompareVersions() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CompareVersions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := CompareVersions("latest", "5.11.0"); err == nil {
+		t.Error("CompareVersions() expected error for non-numeric version")
+	}
+}
@@ -0,0 +1,71 @@
+package probe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed numeric version, e.g. 5.11.0
+type Version []int
+
+// ParseVersion parses dot-separated numeric version.
+// Leading v as well as pre-release and build suffixes (-SNAPSHOT, +build) are ignored
+func ParseVersion(s string) (Version, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, fmt.Errorf("incorrect version %q", s)
+	}
+
+	parts := strings.Split(v, ".")
+	res := make(Version, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("incorrect version %q", s)
+		}
+		res = append(res, n)
+	}
+
+	return res, nil
+}
+
+// Compare returns -1, 0 or 1 if the version is lower, equal or greater than the other one.
+// Missing components are treated as zeros, so 5.11 equals 5.11.0
+func (v Version) Compare(other Version) int {
+	for i := 0; i < max(len(v), len(other)); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(other) {
+			b = other[i]
+		}
+		if a != b {
+			if a < b {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// CompareVersions parses and compares two versions
+func CompareVersions(a, b string) (int, error) {
+	va, err := ParseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := ParseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return va.Compare(vb), nil
+}
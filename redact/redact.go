@@ -0,0 +1,174 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/reportportal/service-index/aggregator"
+)
+
+// Config holds settings of redaction of info and health payloads
+type Config struct {
+	// File is an optional path to YAML or JSON file declaring per-service field rules
+	File string `env:"REDACTION_FILE" envDefault:""`
+	// Secrets enables built-in masking of values looking like secrets
+	Secrets bool `env:"REDACT_SECRETS" envDefault:"true"`
+	// SecretKeys is an additional regular expression of keys holding secrets
+	SecretKeys string `env:"REDACT_SECRET_KEYS" envDefault:""`
+}
+
+// fileConfig represents content of the redaction file
+type fileConfig struct {
+	// Default rules apply to services without rules of their own
+	Default  *ServiceRules            `json:"default,omitempty"`
+	Services map[string]*ServiceRules `json:"services,omitempty"`
+}
+
+// ServiceRules select fields of info and health payloads of a service
+type ServiceRules struct {
+	Info   *Rules `json:"info,omitempty"`
+	Health *Rules `json:"health,omitempty"`
+}
+
+// Redactor removes fields and masks secrets of payloads
+type Redactor struct {
+	defaults *ServiceRules
+	services map[string]*ServiceRules
+	secrets  *secrets
+}
+
+// Load creates redactor of the config, rules are read from the configured file, if any
+func (c *Config) Load() (*Redactor, error) {
+	r := &Redactor{}
+	if c.Secrets {
+		r.secrets = &secrets{}
+		if c.SecretKeys != "" {
+			re, err := regexp.Compile(c.SecretKeys)
+			if err != nil {
+				return nil, fmt.Errorf("incorrect secret keys expression: %w", err)
+			}
+			r.secrets.keys = re
+		}
+	}
+	if c.File == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(c.File)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read redaction config: %w", err)
+	}
+	var fc fileConfig
+	if err := yaml.UnmarshalStrict(data, &fc); err != nil {
+		return nil, fmt.Errorf("unable to parse redaction config: %w", err)
+	}
+	if err := fc.Default.compile(); err != nil {
+		return nil, fmt.Errorf("incorrect default redaction: %w", err)
+	}
+	for name, sr := range fc.Services {
+		if err := sr.compile(); err != nil {
+			return nil, fmt.Errorf("incorrect redaction of %s: %w", name, err)
+		}
+	}
+	r.defaults, r.services = fc.Default, fc.Services
+
+	return r, nil
+}
+
+func (sr *ServiceRules) compile() error {
+	if sr == nil {
+		return nil
+	}
+	if err := sr.Info.compile(); err != nil {
+		return fmt.Errorf("info: %w", err)
+	}
+	if err := sr.Health.compile(); err != nil {
+		return fmt.Errorf("health: %w", err)
+	}
+
+	return nil
+}
+
+// rules returns rules of the service, default ones are used for services without rules
+func (r *Redactor) rules(service string) *ServiceRules {
+	if sr, ok := r.services[service]; ok && sr != nil {
+		return sr
+	}
+	if r.defaults != nil {
+		return r.defaults
+	}
+
+	return &ServiceRules{}
+}
+
+// Info redacts info payload of the service
+func (r *Redactor) Info(service string, body interface{}) interface{} {
+	return r.redact(r.rules(service).Info, body)
+}
+
+// Health redacts health entry of the service, the status is always kept
+func (r *Redactor) Health(service string, entry interface{}) interface{} {
+	res := r.redact(r.rules(service).Health, entry)
+	e, ok := entry.(map[string]interface{})
+	if status, found := e["status"]; ok && found {
+		if m, ok := res.(map[string]interface{}); ok {
+			m["status"] = status
+		}
+	}
+
+	return res
+}
+
+func (r *Redactor) redact(rules *Rules, v interface{}) interface{} {
+	v = rules.apply(v)
+	if r.secrets != nil {
+		v = r.secrets.apply(v)
+	}
+
+	return v
+}
+
+// Aggregator redacts info and health payloads of services
+type Aggregator struct {
+	delegate aggregator.Aggregator
+	redactor *Redactor
+}
+
+// NewAggregator wraps the aggregator with redaction
+func NewAggregator(delegate aggregator.Aggregator, redactor *Redactor) *Aggregator {
+	return &Aggregator{delegate: delegate, redactor: redactor}
+}
+
+// AggregateInfo collects information from info endpoints removing fields hidden by the rules
+func (a *Aggregator) AggregateInfo(ctx context.Context) map[string]interface{} {
+	info := a.delegate.AggregateInfo(ctx)
+	for name, body := range info {
+		info[name] = a.redactor.Info(name, body)
+	}
+
+	return info
+}
+
+// AggregateHealth aggregates information from health endpoints removing fields hidden by the rules
+func (a *Aggregator) AggregateHealth(ctx context.Context) map[string]interface{} {
+	health := a.delegate.AggregateHealth(ctx)
+	for name, entry := range health {
+		health[name] = a.redactor.Health(name, entry)
+	}
+
+	return health
+}
+
+// Services returns names of discovered services
+func (a *Aggregator) Services(ctx context.Context) ([]string, error) {
+	return a.delegate.Services(ctx)
+}
+
+// Discover resolves nodes and describes the result along with the ignored objects
+func (a *Aggregator) Discover(ctx context.Context) *aggregator.Discovery {
+	return a.delegate.Discover(ctx)
+}
@@ -0,0 +1,178 @@
+package redact
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/aggregator/aggregatortest"
+)
+
+func TestRules_apply(t *testing.T) {
+	info := func() map[string]interface{} {
+		return map[string]interface{}{
+			"build": map[string]interface{}{"version": "5.11.0", "name": "api"},
+			"git": map[string]interface{}{
+				"branch": "master",
+				"commit": map[string]interface{}{"id": "abc", "user": map[string]interface{}{"email": "dev@example.com"}},
+			},
+			"env":     "prod",
+			"servers": []interface{}{map[string]interface{}{"url": "a", "weight": 1}, map[string]interface{}{"url": "b"}},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		rules *Rules
+		want  map[string]interface{}
+	}{
+		{
+			name:  "allow",
+			rules: &Rules{Allow: []string{"$.build", "git.commit.id", "env.missing"}},
+			want: map[string]interface{}{
+				"build": map[string]interface{}{"version": "5.11.0", "name": "api"},
+				"git":   map[string]interface{}{"commit": map[string]interface{}{"id": "abc"}},
+			},
+		},
+		{
+			name:  "deny",
+			rules: &Rules{Deny: []string{"git.commit.user", "servers[*].url", "env"}},
+			want: map[string]interface{}{
+				"build":   map[string]interface{}{"version": "5.11.0", "name": "api"},
+				"git":     map[string]interface{}{"branch": "master", "commit": map[string]interface{}{"id": "abc"}},
+				"servers": []interface{}{map[string]interface{}{"weight": 1}, map[string]interface{}{}},
+			},
+		},
+		{
+			name:  "allow and deny",
+			rules: &Rules{Allow: []string{"build", "servers[0]"}, Deny: []string{"build.name", "*.*.weight"}},
+			want: map[string]interface{}{
+				"build":   map[string]interface{}{"version": "5.11.0"},
+				"servers": []interface{}{map[string]interface{}{"url": "a"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rules.compile(); err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.rules.apply(info()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    path
+		wantErr bool
+	}{
+		{path: "$.git.commit.id", want: path{"git", "commit", "id"}},
+		{path: "servers[*].url", want: path{"servers", "*", "url"}},
+		{path: "matrix[0][1]", want: path{"matrix", "0", "1"}},
+		{path: "$", wantErr: true},
+		{path: "a..b", wantErr: true},
+		{path: "a[x]", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.path)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePath(%s) = %v, %v, want %v", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestSecrets_apply(t *testing.T) {
+	s := &secrets{}
+	got := s.apply(map[string]interface{}{
+		"db": map[string]interface{}{
+			"password": "p@ss",
+			"url":      "jdbc:postgresql://db:5432/rp;user=rp;password=p@ss",
+			"dsn":      "postgres://rp:p%40ss@db:5432/rp",
+		},
+		"api.key":     12345,
+		"tokenHeader": nil,
+		"jwt":         "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJ4In0.c2ln",
+		"status":      "UP",
+	})
+	want := map[string]interface{}{
+		"db": map[string]interface{}{
+			"password": Mask,
+			"url":      "jdbc:postgresql://db:5432/rp;user=rp;password=" + Mask,
+			"dsn":      "postgres://rp:" + Mask + "@db:5432/rp",
+		},
+		"api.key":     Mask,
+		"tokenHeader": nil,
+		"jwt":         Mask,
+		"status":      "UP",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("apply() = %v, want %v", got, want)
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "redaction.yaml")
+	config := `
+default:
+  info:
+    allow: [build]
+services:
+  uat:
+    info:
+      deny: [build]
+  api:
+    info:
+      allow: [git]
+    health:
+      allow: [components.cache]
+`
+	if err := os.WriteFile(file, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := (&Config{File: file, Secrets: true}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAggregator(&aggregatortest.Stub{
+		Info: map[string]interface{}{
+			"api": map[string]interface{}{"build": map[string]interface{}{"version": "5.11.0"}, "git": map[string]interface{}{"branch": "master"}},
+			"uat": map[string]interface{}{"build": map[string]interface{}{"version": "5.11.0"}, "git": map[string]interface{}{"branch": "master"}},
+		},
+		Health: map[string]interface{}{
+			"api": map[string]interface{}{"status": aggregator.StatusUp, "components": map[string]interface{}{"db": "UP"}},
+		},
+	}, r)
+
+	info := a.AggregateInfo(context.Background())
+	if want := map[string]interface{}{"git": map[string]interface{}{"branch": "master"}}; !reflect.DeepEqual(info["api"], want) {
+		t.Errorf("AggregateInfo() api = %v, want %v", info["api"], want)
+	}
+	if want := map[string]interface{}{"git": map[string]interface{}{"branch": "master"}}; !reflect.DeepEqual(info["uat"], want) {
+		t.Errorf("AggregateInfo() uat = %v, want %v", info["uat"], want)
+	}
+
+	health := a.AggregateHealth(context.Background())
+	if want := map[string]interface{}{"status": aggregator.StatusUp}; !reflect.DeepEqual(health["api"], want) {
+		t.Errorf("AggregateHealth() api = %v, want status only", health["api"])
+	}
+}
+
+func TestConfig_Load(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "redaction.yaml")
+	if err := os.WriteFile(file, []byte("services:\n  api:\n    info:\n      deny: ['a[b]']\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Config{File: file}).Load(); err == nil {
+		t.Error("Load() error = nil, want incorrect path")
+	}
+	if _, err := (&Config{Secrets: true, SecretKeys: "("}).Load(); err == nil {
+		t.Error("Load() error = nil, want incorrect expression")
+	}
+}
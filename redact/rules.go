@@ -0,0 +1,164 @@
+package redact
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// wildcard matches any key or array index
+const wildcard = "*"
+
+// Rules select fields of a payload. Paths are dot-separated, optionally prefixed with $,
+// e.g. $.git.commit.id, components.*.details or services[*].url
+type Rules struct {
+	// Allow lists the only fields kept along with their children, empty means all of them
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists fields removed along with their children
+	Deny []string `json:"deny,omitempty"`
+
+	allow []path
+	deny  []path
+}
+
+// path is a parsed field path
+type path []string
+
+func (r *Rules) compile() error {
+	if r == nil {
+		return nil
+	}
+	var err error
+	if r.allow, err = parsePaths(r.Allow); err != nil {
+		return err
+	}
+	r.deny, err = parsePaths(r.Deny)
+
+	return err
+}
+
+func parsePaths(ss []string) ([]path, error) {
+	paths := make([]path, 0, len(ss))
+	for _, s := range ss {
+		p, err := parsePath(s)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+
+	return paths, nil
+}
+
+func parsePath(s string) (path, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "$"), ".")
+	if s == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	var p path
+	for _, part := range strings.Split(s, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && rest == "" {
+			return nil, fmt.Errorf("incorrect path %q", s)
+		}
+		if key != "" {
+			p = append(p, key)
+		}
+		// array indexes follow the key, e.g. items[0][*]
+		for rest != "" {
+			idx, tail, ok := strings.Cut(rest, "]")
+			if !ok || (idx != wildcard && !isIndex(idx)) {
+				return nil, fmt.Errorf("incorrect index in path %q", s)
+			}
+			p = append(p, idx)
+			rest = strings.TrimPrefix(tail, "[")
+		}
+	}
+
+	return p, nil
+}
+
+func isIndex(s string) bool {
+	_, err := strconv.Atoi(s)
+
+	return err == nil
+}
+
+// match compares the pattern with the path of a field. Full means the field is matched by the pattern itself
+// or belongs to a matched one, prefix means the field is an ancestor of the fields matched by the pattern
+func (p path) match(field []string) (full, prefix bool) {
+	n := min(len(p), len(field))
+	for i := 0; i < n; i++ {
+		if p[i] != wildcard && p[i] != field[i] {
+			return false, false
+		}
+	}
+	if len(p) <= len(field) {
+		return true, false
+	}
+
+	return false, true
+}
+
+// apply returns a copy of the value limited by the rules
+func (r *Rules) apply(v interface{}) interface{} {
+	if r == nil || (len(r.allow) == 0 && len(r.deny) == 0) {
+		return v
+	}
+	res, _ := r.walk(v, nil, len(r.allow) == 0)
+
+	return res
+}
+
+// walk filters children of the value, allowed means the value is selected by the allow-list entirely
+func (r *Rules) walk(v interface{}, field []string, allowed bool) (interface{}, bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			if c, keep := r.walkChild(child, append(field, k), allowed); keep {
+				res[k] = c
+			}
+		}
+
+		return res, allowed || len(res) > 0
+	case []interface{}:
+		res := make([]interface{}, 0, len(val))
+		for i, child := range val {
+			if c, keep := r.walkChild(child, append(field, strconv.Itoa(i)), allowed); keep {
+				res = append(res, c)
+			}
+		}
+
+		return res, allowed || len(res) > 0
+	default:
+		// scalars are reached partially only if allow-list expects children of them
+		return v, allowed
+	}
+}
+
+func (r *Rules) walkChild(v interface{}, field []string, allowed bool) (interface{}, bool) {
+	for _, p := range r.deny {
+		if full, _ := p.match(field); full {
+			return nil, false
+		}
+	}
+	if !allowed {
+		partial := false
+		for _, p := range r.allow {
+			full, prefix := p.match(field)
+			if full {
+				allowed = true
+
+				break
+			}
+			partial = partial || prefix
+		}
+		if !allowed && !partial {
+			return nil, false
+		}
+	}
+	// the field slice is shared between siblings, so children get their own copy
+	return r.walk(v, append([]string(nil), field...), allowed)
+}
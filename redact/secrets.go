@@ -0,0 +1,77 @@
+package redact
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces redacted values
+const Mask = "******"
+
+var (
+	// secretKeys matches keys of fields holding secrets
+	secretKeys = regexp.MustCompile(`(?i)(passw(or)?d|pwd|secret|token|credential|api[-_.]?key|private[-_.]?key|access[-_.]?key)`)
+	// secretParams matches secrets embedded into connection strings, e.g. jdbc:...;password=...
+	secretParams = regexp.MustCompile(`(?i)((?:passw(?:or)?d|pwd|secret|token|api[-_]?key)=)[^&;\s]+`)
+	// secretValues matches values looking like secrets regardless of their keys: JWT, PEM private keys
+	secretValues = regexp.MustCompile(`^eyJ[\w-]+\.[\w-]+\.[\w-]*$|-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	// urlCredentials matches URLs with user info
+	urlCredentials = regexp.MustCompile(`\b[a-zA-Z][\w+.-]*://[^/\s:@]+:[^/\s@]+@[^\s]+`)
+)
+
+// secrets masks values of secret-like fields and strings
+type secrets struct {
+	keys *regexp.Regexp
+}
+
+func (s *secrets) apply(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			if child != nil && s.secretKey(k) {
+				res[k] = Mask
+
+				continue
+			}
+			res[k] = s.apply(child)
+		}
+
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, child := range val {
+			res[i] = s.apply(child)
+		}
+
+		return res
+	case string:
+		return redactString(val)
+	default:
+		return v
+	}
+}
+
+func (s *secrets) secretKey(k string) bool {
+	return secretKeys.MatchString(k) || (s.keys != nil && s.keys.MatchString(k))
+}
+
+// redactString masks secrets embedded into the string
+func redactString(s string) string {
+	if secretValues.MatchString(s) {
+		return Mask
+	}
+	s = urlCredentials.ReplaceAllStringFunc(s, func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil || u.User == nil {
+			return raw
+		}
+		u.User = url.UserPassword(u.User.Username(), Mask)
+
+		// the mask isn't escaped to keep the value readable
+		return strings.Replace(u.String(), url.QueryEscape(Mask), Mask, 1)
+	})
+
+	return secretParams.ReplaceAllString(s, "${1}"+Mask)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/reportportal/commons-go/v5/server"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/auth"
+	"github.com/reportportal/service-index/badge"
+	"github.com/reportportal/service-index/compat"
+	"github.com/reportportal/service-index/history"
+	"github.com/reportportal/service-index/maintenance"
+	"github.com/reportportal/service-index/monitor"
+	"github.com/reportportal/service-index/statuspage"
+	"github.com/reportportal/service-index/throttle"
+)
+
+// routes holds components serving the API, monitor and history are nil if background checks are disabled
+type routes struct {
+	path    string
+	aggreg  aggregator.Aggregator
+	matrix  *compat.Matrix
+	mon     *monitor.Monitor
+	hist    *history.History
+	authn   *auth.Authenticator
+	badges  *badge.Handler
+	maint   *maintenance.Handler
+	limiter *throttle.Limiter
+	// cached serves cached composite responses, cache hits aren't rate-limited
+	cached func(http.Handler) http.Handler
+}
+
+func (rt *routes) register(router *chi.Mux) {
+	router.Use(middleware.Logger)
+	router.Use(rt.authn.Authenticate)
+	router.Handle(rt.path+"/metrics", promhttp.Handler())
+	router.NotFound(rt.maint.Page(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		http.Redirect(w, rq, rt.path+"/ui/#notfound", http.StatusFound)
+	})).ServeHTTP)
+
+	router.Group(func(r chi.Router) {
+		r.Use(rt.cached, rt.limiter.RateLimit, rt.limiter.Concurrency)
+		r.Handle(rt.path+"/composite/health", compositeHandler(rt.aggreg, aggregator.PayloadHealth, plainHealth(rt.authn)))
+		r.Handle(rt.path+"/composite/health/{service}", compositeHandler(rt.aggreg, aggregator.PayloadHealth, plainHealth(rt.authn)))
+	})
+	router.Group(func(r chi.Router) {
+		r.Use(rt.limiter.RateLimit, rt.limiter.Concurrency)
+		r.Handle(rt.path+"/composite/badge.svg", rt.badges)
+		r.Handle(rt.path+"/composite/badge/{service}.svg", rt.badges)
+		r.Handle(rt.path+"/status", statuspage.New(rt.aggreg, rt.mon, rt.hist).WithDetails(func(ctx context.Context) bool {
+			return rt.authn.Allowed(ctx, auth.LevelAdmin)
+		}))
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(rt.authn.Require(auth.LevelUser))
+		if rt.mon != nil {
+			r.HandleFunc(rt.path+"/composite/health/stream", rt.mon.ServeSSE)
+			r.Handle(rt.path+"/composite/health/ws", rt.mon.WebSocketHandler())
+			r.Handle(rt.path+"/composite/history", rt.hist)
+		}
+	})
+	router.Group(rt.admin)
+	router.Handle(rt.path+"/composite/maintenance", rt.maint.API())
+	router.Handle(rt.path+"/", rt.maint.Page(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, rt.path+"/ui/", http.StatusFound)
+	})))
+	router.Handle(rt.path+"/ui", rt.maint.Page(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, rt.path+"/ui/", http.StatusFound)
+	})))
+}
+
+// admin registers routes exposing details of the deployment
+func (rt *routes) admin(r chi.Router) {
+	r.Use(rt.authn.Require(auth.LevelAdmin), rt.cached, rt.limiter.RateLimit, rt.limiter.Concurrency)
+	r.Handle(rt.path+"/composite/info", compositeHandler(rt.aggreg, aggregator.PayloadInfo, aggregator.Aggregator.AggregateInfo))
+	r.Handle(rt.path+"/composite/info/{service}", compositeHandler(rt.aggreg, aggregator.PayloadInfo, aggregator.Aggregator.AggregateInfo))
+	r.HandleFunc(rt.path+"/composite/discovery", func(w http.ResponseWriter, r *http.Request) {
+		if err := server.WriteJSON(http.StatusOK, rt.aggreg.Discover(r.Context()), w); nil != err {
+			log.Error(err)
+		}
+	})
+	r.HandleFunc(rt.path+"/composite/compatibility", func(w http.ResponseWriter, r *http.Request) {
+		if err := server.WriteJSON(http.StatusOK, rt.matrix.Evaluate(rt.aggreg.AggregateInfo(r.Context())), w); nil != err {
+			log.Error(err)
+		}
+	})
+}
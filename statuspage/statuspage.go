@@ -0,0 +1,244 @@
+package statuspage
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/history"
+	"github.com/reportportal/service-index/monitor"
+)
+
+const (
+	// incidentsLimit is a number of the most recent incidents shown
+	incidentsLimit = 20
+	// incidentsPeriod is a period incidents are looked up within
+	incidentsPeriod = 7 * 24 * time.Hour
+	// refreshSeconds is an interval of page auto-refresh
+	refreshSeconds = 30
+)
+
+//go:embed status.html
+var pageHTML string
+
+var pageTmpl = template.Must(template.New("status").Funcs(template.FuncMap{
+	"statusClass": statusClass,
+	"ago": func(t time.Time) string {
+		return duration(time.Since(t)) + " ago"
+	},
+	"duration": duration,
+}).Parse(pageHTML))
+
+// Page renders human-readable status of the components, it doesn't depend on any external assets
+type Page struct {
+	aggreg aggregator.Aggregator
+	mon    *monitor.Monitor
+	hist   *history.History
+	// details reports whether versions and errors of components are shown to the client
+	details func(ctx context.Context) bool
+}
+
+// Component is a row of the status table
+type Component struct {
+	Name    string
+	Version string
+	Status  string
+	Since   time.Time
+	Error   string
+}
+
+// Incident is a period a component wasn't UP
+type Incident struct {
+	Service  string
+	Status   string
+	Start    time.Time
+	Duration time.Duration
+	Ongoing  bool
+}
+
+type pageData struct {
+	Components  []Component
+	Incidents   []Incident
+	Operational bool
+	Overall     string
+	Degraded    int
+	CheckedAt   time.Time
+	Refresh     int
+}
+
+// New creates status page. Monitor and history are optional, health is aggregated on request without the monitor
+func New(aggreg aggregator.Aggregator, mon *monitor.Monitor, hist *history.History) *Page {
+	return &Page{aggreg: aggreg, mon: mon, hist: hist}
+}
+
+// WithDetails limits versions and errors of components to the clients allowed by the func
+func (p *Page) WithDetails(allowed func(ctx context.Context) bool) *Page {
+	p.details = allowed
+
+	return p
+}
+
+// ServeHTTP renders the status page
+func (p *Page) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := pageTmpl.Execute(&buf, p.collect(r.Context())); err != nil {
+		log.Errorf("Unable to render status page: %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Error(err)
+	}
+}
+
+func (p *Page) collect(ctx context.Context) *pageData {
+	details := p.details == nil || p.details(ctx)
+	info := make(chan map[string]interface{}, 1)
+	if details {
+		go func() {
+			info <- p.aggreg.AggregateInfo(ctx)
+		}()
+	} else {
+		info <- nil
+	}
+
+	var health map[string]interface{}
+	var checkedAt time.Time
+	if p.mon != nil {
+		health, checkedAt = p.mon.Snapshot()
+	}
+	if checkedAt.IsZero() {
+		health, checkedAt = p.aggreg.AggregateHealth(ctx), time.Now()
+	}
+
+	var rp *history.Report
+	if p.hist != nil {
+		rp = p.hist.Query("", checkedAt.Add(-incidentsPeriod), checkedAt)
+	}
+
+	data := &pageData{CheckedAt: checkedAt, Refresh: refreshSeconds, Operational: true}
+	versions := versionsOf(<-info)
+	for _, name := range aggregator.Names(health) {
+		entry, _ := health[name].(map[string]interface{})
+		c := Component{Name: name, Version: versions[name], Status: monitor.StatusOf(entry), Since: sinceOf(entry, rp, name)}
+		if details {
+			c.Error, _ = entry["error"].(string)
+		}
+		data.add(c)
+	}
+	data.Overall = aggregator.Overall(health)
+	data.Incidents = incidentsOf(rp, checkedAt)
+
+	return data
+}
+
+// add appends the component counting it if it isn't UP
+func (d *pageData) add(c Component) {
+	d.Components = append(d.Components, c)
+	if c.Status != aggregator.StatusUp {
+		d.Operational = false
+		d.Degraded++
+	}
+}
+
+// sinceOf returns time the component has its current status since, either reported by health or taken from history
+func sinceOf(entry map[string]interface{}, rp *history.Report, name string) time.Time {
+	if since, ok := entry["since"].(time.Time); ok {
+		return since
+	}
+	if rp == nil || rp.Services[name] == nil {
+		return time.Time{}
+	}
+	ts := rp.Services[name].Transitions
+	if len(ts) == 0 {
+		return time.Time{}
+	}
+
+	return ts[len(ts)-1].Time
+}
+
+// incidentsOf collects the most recent periods components weren't UP
+func incidentsOf(rp *history.Report, now time.Time) []Incident {
+	if rp == nil {
+		return nil
+	}
+
+	var incidents []Incident
+	for service, sr := range rp.Services {
+		ts := sr.Transitions
+		for i, t := range ts {
+			if t.Status == aggregator.StatusUp || t.Status == aggregator.StatusUnknown {
+				continue
+			}
+			inc := Incident{Service: service, Status: t.Status, Start: t.Time, Ongoing: i == len(ts)-1}
+			if inc.Ongoing {
+				inc.Duration = now.Sub(t.Time)
+			} else {
+				inc.Duration = ts[i+1].Time.Sub(t.Time)
+			}
+			incidents = append(incidents, inc)
+		}
+	}
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].Start.After(incidents[j].Start)
+	})
+	if len(incidents) > incidentsLimit {
+		incidents = incidents[:incidentsLimit]
+	}
+
+	return incidents
+}
+
+// versionsOf extracts build.version of services from composite info
+func versionsOf(info map[string]interface{}) map[string]string {
+	versions := make(map[string]string, len(info))
+	for name, rs := range info {
+		body, _ := rs.(map[string]interface{})
+		build, _ := body["build"].(map[string]interface{})
+		if v, ok := build["version"].(string); ok {
+			versions[name] = v
+		}
+	}
+
+	return versions
+}
+
+func statusClass(status string) string {
+	switch status {
+	case aggregator.StatusUp:
+		return "up"
+	case aggregator.StatusDown, aggregator.StatusTimeout:
+		return "down"
+	case aggregator.StatusUnknown:
+		return "unknown"
+	default:
+		return "warn"
+	}
+}
+
+// duration formats duration with the precision sufficient for humans, e.g. 2h 5m
+func duration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
@@ -0,0 +1,81 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/aggregator/aggregatortest"
+	"github.com/reportportal/service-index/history"
+)
+
+func TestPage_ServeHTTP(t *testing.T) {
+	aggreg := &aggregatortest.Stub{
+		Info: map[string]interface{}{
+			"api": map[string]interface{}{"build": map[string]interface{}{"version": "5.11.0"}},
+		},
+		Health: map[string]interface{}{
+			"api": map[string]interface{}{"status": aggregator.StatusUp},
+			"uat": map[string]interface{}{"status": aggregator.StatusDown, "error": "connection <refused>"},
+		},
+	}
+	hist, err := history.New(&history.Config{Size: 10, SLOTarget: 99.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	hist.Record("uat", history.Transition{Time: now.Add(-time.Hour), Status: aggregator.StatusUp})
+	hist.Record("uat", history.Transition{Time: now.Add(-5 * time.Minute), Status: aggregator.StatusDown})
+
+	rr := httptest.NewRecorder()
+	New(aggreg, nil, hist).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() code = %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("ServeHTTP() content type = %s", ct)
+	}
+
+	body := rr.Body.String()
+	wants := []string{
+		// failure of a non-critical component degrades the deployment
+		`class="banner warn"`, "1 of 2 components are not operational",
+		"5.11.0", "connection &lt;refused&gt;", "5m ago", "ongoing, 5m",
+	}
+	for _, want := range wants {
+		if !strings.Contains(body, want) {
+			t.Errorf("ServeHTTP() body doesn't contain %q", want)
+		}
+	}
+	if strings.Contains(body, "http://") || strings.Contains(body, "https://") {
+		t.Error("ServeHTTP() body refers external assets")
+	}
+}
+
+func TestIncidentsOf(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rp := &history.Report{Services: map[string]*history.ServiceReport{
+		"api": {Transitions: []history.Transition{
+			{Time: start, Status: aggregator.StatusUp},
+			{Time: start.Add(time.Minute), Status: aggregator.StatusDown},
+			{Time: start.Add(3 * time.Minute), Status: aggregator.StatusUp},
+		}},
+		"uat": {Transitions: []history.Transition{
+			{Time: start.Add(2 * time.Minute), Status: aggregator.StatusTimeout},
+		}},
+	}}
+
+	incidents := incidentsOf(rp, start.Add(10*time.Minute))
+	if len(incidents) != 2 {
+		t.Fatalf("incidentsOf() = %+v, want 2 incidents", incidents)
+	}
+	if inc := incidents[0]; inc.Service != "uat" || !inc.Ongoing || inc.Duration != 8*time.Minute {
+		t.Errorf("incidentsOf()[0] = %+v", inc)
+	}
+	if inc := incidents[1]; inc.Service != "api" || inc.Ongoing || inc.Duration != 2*time.Minute {
+		t.Errorf("incidentsOf()[1] = %+v", inc)
+	}
+}
@@ -0,0 +1,172 @@
+package throttle
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reportportal/commons-go/v5/server"
+	"golang.org/x/time/rate"
+
+	"github.com/reportportal/service-index/auth"
+)
+
+// idleTTL is a period of inactivity after which limiter of a client is dropped
+const idleTTL = 10 * time.Minute
+
+var (
+	errRateLimited = errors.New("too many requests")
+	errOverloaded  = errors.New("too many aggregations in progress")
+)
+
+// Config holds settings of request throttling
+type Config struct {
+	// Rate is a number of requests per second allowed to a single client, zero disables rate limiting
+	Rate  float64 `env:"RATE_LIMIT"       envDefault:"0"`
+	Burst int     `env:"RATE_LIMIT_BURST" envDefault:"20"`
+	// TrustProxy identifies clients by X-Forwarded-For and X-Real-IP headers set by a reverse proxy
+	TrustProxy bool `env:"RATE_LIMIT_TRUST_PROXY" envDefault:"false"`
+	// TrustedHops is a number of reverse proxies appending to X-Forwarded-For, the client address is taken
+	// from that position counting from the right since the preceding entries may be forged by the client
+	TrustedHops int `env:"RATE_LIMIT_TRUSTED_HOPS" envDefault:"1"`
+	// Concurrency limits number of aggregations served simultaneously, zero means no limit
+	Concurrency int `env:"AGGREGATION_CONCURRENCY" envDefault:"8"`
+}
+
+// Limiter rate-limits clients and caps concurrency of requests
+type Limiter struct {
+	cfg *Config
+	now func() time.Time
+	sem chan struct{}
+
+	mu      sync.Mutex
+	clients map[string]*client
+	swept   time.Time
+}
+
+type client struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// New creates limiter of the config
+func New(cfg *Config) *Limiter {
+	l := &Limiter{cfg: cfg, now: time.Now, clients: map[string]*client{}}
+	if cfg.Concurrency > 0 {
+		l.sem = make(chan struct{}, cfg.Concurrency)
+	}
+
+	return l
+}
+
+// RateLimit rejects requests of clients exceeding the rate with 429 status
+func (l *Limiter) RateLimit(next http.Handler) http.Handler {
+	if l.cfg.Rate <= 0 {
+		return next
+	}
+
+	return server.Handler{H: func(w http.ResponseWriter, r *http.Request) error {
+		if wait := l.reserve(l.clientID(r)); wait > 0 {
+			return tooManyRequests(w, wait, errRateLimited)
+		}
+		next.ServeHTTP(w, r)
+
+		return nil
+	}}
+}
+
+// Concurrency rejects requests with 429 status while the configured number of them is in progress
+func (l *Limiter) Concurrency(next http.Handler) http.Handler {
+	if l.sem == nil {
+		return next
+	}
+
+	return server.Handler{H: func(w http.ResponseWriter, r *http.Request) error {
+		select {
+		case l.sem <- struct{}{}:
+			defer func() { <-l.sem }()
+		default:
+			return tooManyRequests(w, time.Second, errOverloaded)
+		}
+		next.ServeHTTP(w, r)
+
+		return nil
+	}}
+}
+
+// reserve takes a token of the client, returns time to wait if there is none
+func (l *Limiter) reserve(id string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	c, ok := l.clients[id]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(rate.Limit(l.cfg.Rate), max(l.cfg.Burst, 1))}
+		l.clients[id] = c
+	}
+	c.seen = now
+
+	rs := c.limiter.ReserveN(now, 1)
+	if wait := rs.DelayFrom(now); wait > 0 {
+		// rejected requests don't consume tokens
+		rs.CancelAt(now)
+
+		return wait
+	}
+
+	return 0
+}
+
+// sweep drops limiters of idle clients
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < idleTTL {
+		return
+	}
+	l.swept = now
+	for id, c := range l.clients {
+		if now.Sub(c.seen) > idleTTL {
+			delete(l.clients, id)
+		}
+	}
+}
+
+// clientID identifies client either by authenticated principal or by address
+func (l *Limiter) clientID(r *http.Request) string {
+	if p := auth.PrincipalFrom(r.Context()); p != nil {
+		return "principal:" + p.Subject
+	}
+	if l.cfg.TrustProxy {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			return forwardedFor(xff, l.cfg.TrustedHops)
+		}
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return ip
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// forwardedFor picks the client address appended by the outermost of trusted proxies
+func forwardedFor(xff string, hops int) string {
+	ips := strings.Split(xff, ",")
+	i := len(ips) - max(hops, 1)
+
+	return strings.TrimSpace(ips[max(i, 0)])
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, err error) error {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+
+	return server.ToStatusError(http.StatusTooManyRequests, err)
+}
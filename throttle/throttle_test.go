@@ -0,0 +1,117 @@
+package throttle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter_RateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(&Config{Rate: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+	h := l.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	serve := func(addr string) *httptest.ResponseRecorder {
+		rq := httptest.NewRequest(http.MethodGet, "/composite/health", nil)
+		rq.RemoteAddr = addr
+		rs := httptest.NewRecorder()
+		h.ServeHTTP(rs, rq)
+
+		return rs
+	}
+
+	tests := []struct {
+		name   string
+		addr   string
+		after  time.Duration
+		status int
+		retry  string
+	}{
+		{name: "first", addr: "10.0.0.1:1000", status: http.StatusOK},
+		{name: "burst", addr: "10.0.0.1:1001", status: http.StatusOK},
+		{name: "exceeded", addr: "10.0.0.1:1002", status: http.StatusTooManyRequests, retry: "1"},
+		{name: "another client", addr: "10.0.0.2:1000", status: http.StatusOK},
+		{name: "refilled", addr: "10.0.0.1:1003", after: time.Second, status: http.StatusOK},
+		{name: "exceeded again", addr: "10.0.0.1:1004", status: http.StatusTooManyRequests, retry: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.after)
+			rs := serve(tt.addr)
+			if rs.Code != tt.status {
+				t.Errorf("status = %d, want %d", rs.Code, tt.status)
+			}
+			if got := rs.Header().Get("Retry-After"); got != tt.retry {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retry)
+			}
+		})
+	}
+}
+
+func TestLimiter_Concurrency(t *testing.T) {
+	l := New(&Config{Concurrency: 1})
+	entered, release := make(chan struct{}), make(chan struct{})
+	h := l.Concurrency(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(entered)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	<-entered
+
+	rs := httptest.NewRecorder()
+	h.ServeHTTP(rs, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rs.Code != http.StatusTooManyRequests || rs.Header().Get("Retry-After") != "1" {
+		t.Errorf("overloaded response = %d, Retry-After %q", rs.Code, rs.Header().Get("Retry-After"))
+	}
+	close(release)
+	<-done
+
+	h = l.Concurrency(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	rs = httptest.NewRecorder()
+	h.ServeHTTP(rs, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rs.Code != http.StatusOK {
+		t.Errorf("released response = %d, want 200", rs.Code)
+	}
+}
+
+func TestLimiter_clientID(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		hops       int
+		xff        string
+		realIP     string
+		want       string
+	}{
+		{name: "remote address", want: "192.0.2.1"},
+		{name: "untrusted proxy", xff: "203.0.113.5", want: "192.0.2.1"},
+		{name: "forwarded", trustProxy: true, hops: 1, xff: "203.0.113.5", want: "203.0.113.5"},
+		{name: "forged entries", trustProxy: true, hops: 1, xff: "10.0.0.1, 203.0.113.5", want: "203.0.113.5"},
+		{name: "proxy chain", trustProxy: true, hops: 2, xff: "10.0.0.1, 203.0.113.5, 10.1.0.1", want: "203.0.113.5"},
+		{name: "short chain", trustProxy: true, hops: 3, xff: "203.0.113.5, 10.1.0.1", want: "203.0.113.5"},
+		{name: "real ip", trustProxy: true, realIP: "203.0.113.6", want: "203.0.113.6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(&Config{TrustProxy: tt.trustProxy, TrustedHops: tt.hops})
+			rq := httptest.NewRequest(http.MethodGet, "/", nil)
+			rq.RemoteAddr = "192.0.2.1:4321"
+			if tt.xff != "" {
+				rq.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				rq.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := l.clientID(rq); got != tt.want {
+				t.Errorf("clientID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
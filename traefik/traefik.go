@@ -1,17 +1,19 @@
 package traefik
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/go-resty/resty/v2"
 	log "github.com/sirupsen/logrus"
 	"github.com/vulcand/predicate"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/probe"
 )
 
 const (
@@ -19,12 +21,20 @@ const (
 	traefikV1ProvidersURL    = "/api/providers/docker"
 	traefikV2ServicesURL     = "/api/http/services"
 	traefikRawDataURL        = "/api/rawdata"
+
+	sourceBackend   = "Traefik backend "
+	sourceService   = "Traefik service "
+	reasonNoServers = "no servers declared"
+
+	// schemeH2C is a scheme of Traefik servers speaking cleartext HTTP/2, typically gRPC ones
+	schemeH2C = "h2c"
 )
 
 var (
 	errEmptyResponse = errors.New("response is empty")
 	errGetHealth     = errors.New("unable to update health info")
 	errPathParsing   = errors.New("unable to parse path")
+	errNoGRPCInfo    = errors.New("info isn't available for gRPC probes")
 )
 
 // Providers represents traefik response model
@@ -60,11 +70,20 @@ type Aggregator struct {
 	v2             bool
 	containerBased bool
 	usePathPrefix  bool
+	probes         *probe.Client
+	// api queries Traefik API, unlike r it isn't restricted by the probe target policy
+	api *resty.Client
 }
 
 // NodeInfo embeds node-related information
 type NodeInfo struct {
 	URL string
+	// service is a name of the node in composite responses
+	service string
+	// probeType is either http or grpc
+	probeType string
+	// source is a Traefik object the node is discovered from
+	source string
 }
 
 // GetInfoEndpoint returns info endpoint URL
@@ -88,45 +107,73 @@ func (ni *NodeInfo) GetHealthEndpoint() string {
 }
 
 // NewAggregator creates new traefik aggregator
-func NewAggregator(traefikURL string, traefikV2, containerBased, usePathPrefix bool, timeout time.Duration) *Aggregator {
+func NewAggregator(
+	traefikURL string,
+	traefikV2, containerBased, usePathPrefix bool,
+	probes *probe.Client,
+) *Aggregator {
 	return &Aggregator{
-		r: resty.NewWithClient(&http.Client{
-			Timeout: timeout,
-		}),
+		r:              probes.NewRestClient(),
+		api:            probes.NewAPIRestClient(),
 		traefikURL:     traefikURL,
 		v2:             traefikV2,
 		containerBased: containerBased,
 		usePathPrefix:  usePathPrefix,
+		probes:         probes,
 	}
 }
 
 // AggregateHealth aggregates health info
-func (a *Aggregator) AggregateHealth() map[string]interface{} {
-	return a.aggregate(func(ni *NodeInfo) (interface{}, error) {
+func (a *Aggregator) AggregateHealth(ctx context.Context) map[string]interface{} {
+	health, unfinished := a.aggregate(ctx, func(ctx context.Context, ni *NodeInfo) (interface{}, error) {
 		var rs map[string]interface{}
 		if ni.GetHealthEndpoint() != "" {
-			_, e := a.r.R().SetResult(&rs).SetError(&rs).Get(ni.GetHealthEndpoint())
-			if nil != e {
-				rs = map[string]interface{}{"status": "DOWN"}
-			}
+			ctx, cancel := a.probes.HealthContext(ctx, ni.service)
+			defer cancel()
+
+			e := a.probes.Do(ni.service, func() error {
+				var err error
+				rs, err = a.checkHealth(ctx, ni)
+
+				return err
+			})
+			rs = a.probes.HealthResult(ni.service, rs, e)
 		} else {
-			rs = map[string]interface{}{"status": "UNKNOWN"}
+			rs = map[string]interface{}{"status": aggregator.StatusUnknown}
 		}
 
 		return rs, nil
 	})
+
+	return aggregator.MarkTimeouts(health, unfinished)
 }
 
 // AggregateInfo aggregates info
-func (a *Aggregator) AggregateInfo() map[string]interface{} {
-	return a.aggregate(func(info *NodeInfo) (interface{}, error) {
+func (a *Aggregator) AggregateInfo(ctx context.Context) map[string]interface{} {
+	info, _ := a.aggregate(ctx, func(ctx context.Context, info *NodeInfo) (interface{}, error) {
+		if info.probeType == probe.TypeGRPC {
+			return nil, errNoGRPCInfo
+		}
+		ctx, cancel := a.probes.InfoContext(ctx, info.service)
+		defer cancel()
+
 		var rs map[string]interface{}
-		_, e := a.r.R().SetResult(&rs).Get(info.GetInfoEndpoint())
+		var resp *resty.Response
+		rq, e := a.newRequest(ctx, info)
+		if nil == e {
+			e = a.probes.Do(info.service, func() error {
+				var err error
+				resp, err = rq.SetResult(&rs).Get(info.GetInfoEndpoint())
+
+				return err
+			})
+		}
 		if nil != e {
 			log.Errorf("Unable to aggregate info: %v", e)
 
 			return nil, fmt.Errorf("unable to aggregate nodes info: %w", e)
 		}
+		rs = a.probes.ValidateInfo(info.service, resp, rs)
 		if nil == rs {
 			log.Error("Unable to collect info endpoint response")
 
@@ -135,52 +182,120 @@ func (a *Aggregator) AggregateInfo() map[string]interface{} {
 
 		return rs, nil
 	})
+
+	return info
+}
+
+// Services returns names of discovered services
+func (a *Aggregator) Services(ctx context.Context) ([]string, error) {
+	nodesInfo, err := a.discover(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregator.Names(nodesInfo), nil
+}
+
+// Discover resolves nodes and describes the result along with the ignored objects
+func (a *Aggregator) Discover(ctx context.Context) *aggregator.Discovery {
+	d := aggregator.NewDiscovery()
+	nodesInfo, err := a.discover(ctx, d)
+	if err != nil {
+		d.Fail(err)
+
+		return d
+	}
+	for _, ni := range nodesInfo {
+		d.Add(aggregator.DiscoveredNode{
+			Service:        ni.service,
+			Source:         ni.source,
+			Target:         ni.URL,
+			ProbeType:      ni.probeType,
+			InfoEndpoint:   ni.GetInfoEndpoint(),
+			HealthEndpoint: ni.GetHealthEndpoint(),
+		})
+	}
+
+	return d
 }
 
-func (a *Aggregator) aggregate(f func(ni *NodeInfo) (interface{}, error)) map[string]interface{} {
+// checkHealth probes health of the node according to its probe type
+func (a *Aggregator) checkHealth(ctx context.Context, ni *NodeInfo) (map[string]interface{}, error) {
+	if ni.probeType == probe.TypeGRPC {
+		u, err := url.Parse(ni.URL)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse URL: %w", err)
+		}
+
+		return a.probes.GRPCHealth(ctx, ni.service, u.Host, u.Scheme == probe.SchemeHTTPS)
+	}
+
+	rq, err := a.newRequest(ctx, ni)
+	if err != nil {
+		return nil, err
+	}
+	var rs map[string]interface{}
+	resp, err := rq.SetResult(&rs).SetError(&rs).Get(ni.GetHealthEndpoint())
+	if err != nil {
+		return nil, err
+	}
+
+	return a.probes.ValidateHealth(ni.service, resp, rs), nil
+}
+
+func (a *Aggregator) aggregate(
+	ctx context.Context,
+	f func(ctx context.Context, ni *NodeInfo) (interface{}, error),
+) (map[string]interface{}, []string) {
+	ctx, cancel := a.probes.AggregationContext(ctx)
+	defer cancel()
+
+	nodesInfo, err := a.discover(ctx, nil)
+	if err != nil {
+		return map[string]interface{}{}, nil
+	}
+
+	return aggregator.Fanout(ctx, nodesInfo, a.probes.Concurrency(), f)
+}
+
+// discover resolves nodes using Traefik API according to the configured mode.
+// Ignored objects are recorded to the discovery
+func (a *Aggregator) discover(ctx context.Context, d *aggregator.Discovery) (map[string]*NodeInfo, error) {
+	ctx, cancel := a.probes.DiscoveryContext(ctx)
+	defer cancel()
+
 	var nodesInfo map[string]*NodeInfo
 	var err error
 	if a.containerBased {
 		if a.v2 {
-			nodesInfo, err = a.getNodesInfoV2()
+			nodesInfo, err = a.getNodesInfoV2(ctx, d)
 		} else if a.usePathPrefix {
-			nodesInfo, err = a.getNodesInfoWithPath()
+			nodesInfo, err = a.getNodesInfoWithPath(ctx, d)
 		} else {
-			nodesInfo, err = a.getNodesInfo()
+			nodesInfo, err = a.getNodesInfo(ctx, d)
 		}
 	} else {
-		nodesInfo, err = a.getNodesInfoVLocal()
+		nodesInfo, err = a.getNodesInfoVLocal(ctx, d)
 	}
-
 	if err != nil {
-		return map[string]interface{}{}
+		return nil, err
 	}
 
-	nodeLen := len(nodesInfo)
-	aggregated := make(map[string]interface{}, nodeLen)
-	var wg sync.WaitGroup
-
-	wg.Add(nodeLen)
-	var mu sync.Mutex
 	for node, info := range nodesInfo {
-		go func(node string, info *NodeInfo) {
-			defer wg.Done()
-			res, err := f(info)
-			if nil == err {
-				mu.Lock()
-				aggregated[node] = res
-				mu.Unlock()
-			}
-		}(node, info)
+		info.service = node
+		a.applyScheme(node, info)
+		if err := a.probes.CheckTarget(info.URL); err != nil {
+			d.Skip(info.source, err.Error())
+			delete(nodesInfo, node)
+		}
 	}
-	wg.Wait()
 
-	return aggregated
+	return nodesInfo, nil
 }
 
-func (a *Aggregator) getNodesInfo() (map[string]*NodeInfo, error) {
+func (a *Aggregator) getNodesInfo(ctx context.Context, d *aggregator.Discovery) (map[string]*NodeInfo, error) {
 	var provider Provider
-	_, err := a.r.R().SetResult(&provider).Get(a.traefikURL + traefikV1ProvidersURL)
+	_, err := a.api.R().SetContext(ctx).SetResult(&provider).Get(a.traefikURL + traefikV1ProvidersURL)
 	if nil != err {
 		return nil, fmt.Errorf("unable to GET Traefik providers: %w", err)
 	}
@@ -188,16 +303,23 @@ func (a *Aggregator) getNodesInfo() (map[string]*NodeInfo, error) {
 	nodesInfo := make(map[string]*NodeInfo, len(provider.Backends))
 
 	for bName, b := range provider.Backends {
+		source := sourceBackend + bName
+		server := getFirstNode(b.Servers)
+		if server == nil {
+			d.Skip(source, reasonNoServers)
+
+			continue
+		}
 		backName := bName[strings.LastIndex(bName, "backend-")+len("backend-"):]
-		nodesInfo[backName] = &NodeInfo{URL: getFirstNode(b.Servers).URL}
+		nodesInfo[backName] = &NodeInfo{URL: server.URL, source: source}
 	}
 
 	return nodesInfo, nil
 }
 
-func (a *Aggregator) getNodesInfoV2() (map[string]*NodeInfo, error) {
+func (a *Aggregator) getNodesInfoV2(ctx context.Context, d *aggregator.Discovery) (map[string]*NodeInfo, error) {
 	var serviceInfo []*serviceRepresentation
-	rs, err := a.r.R().SetResult(&serviceInfo).Get(a.traefikURL + traefikV2ServicesURL)
+	rs, err := a.api.R().SetContext(ctx).SetResult(&serviceInfo).Get(a.traefikURL + traefikV2ServicesURL)
 	if nil != err {
 		return nil, fmt.Errorf("unable to GET Traefik services info: %w", err)
 	}
@@ -208,18 +330,22 @@ func (a *Aggregator) getNodesInfoV2() (map[string]*NodeInfo, error) {
 	nodesInfo := make(map[string]*NodeInfo, len(serviceInfo))
 
 	for _, b := range serviceInfo {
-		backName := b.Name[:strings.LastIndex(b.Name, "@")]
-		if b.LoadBalancer != nil {
-			nodesInfo[backName] = &NodeInfo{URL: b.LoadBalancer.Servers[0].URL}
+		source := sourceService + b.Name
+		if b.LoadBalancer == nil || len(b.LoadBalancer.Servers) == 0 {
+			d.Skip(source, reasonNoServers)
+
+			continue
 		}
+		backName := b.Name[:strings.LastIndex(b.Name, "@")]
+		nodesInfo[backName] = &NodeInfo{URL: b.LoadBalancer.Servers[0].URL, source: source}
 	}
 
 	return nodesInfo, nil
 }
 
-func (a *Aggregator) getNodesInfoVLocal() (map[string]*NodeInfo, error) {
+func (a *Aggregator) getNodesInfoVLocal(ctx context.Context, d *aggregator.Discovery) (map[string]*NodeInfo, error) {
 	var provider LocalProvider
-	_, err := a.r.R().SetResult(&provider).Get(a.traefikURL + traefikLocalProvidersURL)
+	_, err := a.api.R().SetContext(ctx).SetResult(&provider).Get(a.traefikURL + traefikLocalProvidersURL)
 	if nil != err {
 		return nil, fmt.Errorf("unable to GET Traefik providers: %w", err)
 	}
@@ -227,15 +353,22 @@ func (a *Aggregator) getNodesInfoVLocal() (map[string]*NodeInfo, error) {
 	nodesInfo := make(map[string]*NodeInfo, len(provider.Provider.Backends))
 
 	for bName, b := range provider.Provider.Backends {
-		nodesInfo[bName] = &NodeInfo{URL: getFirstNode(b.Servers).URL}
+		source := sourceBackend + bName
+		server := getFirstNode(b.Servers)
+		if server == nil {
+			d.Skip(source, reasonNoServers)
+
+			continue
+		}
+		nodesInfo[bName] = &NodeInfo{URL: server.URL, source: source}
 	}
 
 	return nodesInfo, nil
 }
 
-func (a *Aggregator) getNodesInfoWithPath() (map[string]*NodeInfo, error) {
+func (a *Aggregator) getNodesInfoWithPath(ctx context.Context, d *aggregator.Discovery) (map[string]*NodeInfo, error) {
 	var rawData RawData
-	rs, err := a.r.R().SetResult(&rawData).Get(a.traefikURL + traefikRawDataURL)
+	rs, err := a.api.R().SetContext(ctx).SetResult(&rawData).Get(a.traefikURL + traefikRawDataURL)
 
 	if nil != err {
 		return nil, fmt.Errorf("unable to GET Traefik raw data: %w", err)
@@ -248,20 +381,53 @@ func (a *Aggregator) getNodesInfoWithPath() (map[string]*NodeInfo, error) {
 	nodesInfo := make(map[string]*NodeInfo, len(rawData.Services))
 
 	for sName, s := range rawData.Services {
-		if s.LoadBalancer != nil {
-			backName := sName[:strings.LastIndex(sName, "@")]
-			sURL := s.LoadBalancer.Servers[0].URL
-			path, err := getPath(rawData.Routers[sName].Rule)
-			if nil != err {
-				return nil, fmt.Errorf("unable to parse path: %w", err)
-			}
-			nodesInfo[backName] = &NodeInfo{URL: sURL + path}
+		source := sourceService + sName
+		if s.LoadBalancer == nil || len(s.LoadBalancer.Servers) == 0 {
+			d.Skip(source, reasonNoServers)
+
+			continue
 		}
+		backName := sName[:strings.LastIndex(sName, "@")]
+		sURL := s.LoadBalancer.Servers[0].URL
+		path, err := getPath(rawData.Routers[sName].Rule)
+		if nil != err {
+			return nil, fmt.Errorf("unable to parse path: %w", err)
+		}
+		nodesInfo[backName] = &NodeInfo{URL: sURL + path, source: source}
 	}
 
 	return nodesInfo, nil
 }
 
+// newRequest creates a request to the node authenticated with credentials configured for the service
+func (a *Aggregator) newRequest(ctx context.Context, ni *NodeInfo) (*resty.Request, error) {
+	rq := a.r.R().SetContext(ctx)
+	if err := a.probes.Authenticate(ni.service, rq); err != nil {
+		return nil, err
+	}
+
+	return rq, nil
+}
+
+// applyScheme overrides scheme of the node URL if configured for the service.
+// Servers declared with h2c scheme are probed using gRPC health checking protocol
+func (a *Aggregator) applyScheme(node string, info *NodeInfo) {
+	u, err := url.Parse(info.URL)
+	if nil != err {
+		log.Errorf("Unable to parse URL of service %s: %v", node, err)
+
+		return
+	}
+	declared := probe.TypeHTTP
+	if u.Scheme == schemeH2C {
+		declared = probe.TypeGRPC
+		u.Scheme = probe.SchemeHTTP
+	}
+	info.probeType = a.probes.TypeFor(node, declared)
+	u.Scheme = a.probes.SchemeFor(node, u.Scheme)
+	info.URL = u.String()
+}
+
 func getFirstNode(m map[string]*Server) *Server {
 	for _, v := range m {
 		return v
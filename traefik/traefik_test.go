@@ -1,6 +1,16 @@
 package traefik
 
-import "testing"
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/reportportal/service-index/aggregator"
+	"github.com/reportportal/service-index/probe"
+)
 
 func Test_getPath(t *testing.T) {
 	type args struct {
@@ -44,3 +54,133 @@ func Test_getPath(t *testing.T) {
 		})
 	}
 }
+
+func node(service, source, target, probeType string) aggregator.DiscoveredNode {
+	return aggregator.DiscoveredNode{
+		Service:        service,
+		Source:         source,
+		Target:         target,
+		ProbeType:      probeType,
+		InfoEndpoint:   target + "/info",
+		HealthEndpoint: target + "/health",
+	}
+}
+
+func TestAggregator_Discover(t *testing.T) {
+	tests := []struct {
+		name                       string
+		v2, containerBased, prefix bool
+		path, body                 string
+		want                       []aggregator.DiscoveredNode
+		wantSkipped                []aggregator.SkippedNode
+	}{
+		{
+			name:           "v1 docker provider",
+			containerBased: true,
+			path:           traefikV1ProvidersURL,
+			body: `{"backends": {
+				"backend-api": {"servers": {"server-1": {"url": "http://api:8585"}}},
+				"backend-empty": {"servers": {}}
+			}}`,
+			want:        []aggregator.DiscoveredNode{node("api", "Traefik backend backend-api", "http://api:8585", probe.TypeHTTP)},
+			wantSkipped: []aggregator.SkippedNode{{Source: "Traefik backend backend-empty", Reason: reasonNoServers}},
+		},
+		{
+			name:           "v2 services",
+			v2:             true,
+			containerBased: true,
+			path:           traefikV2ServicesURL,
+			body: `[
+				{"name": "analyzer@docker", "loadBalancer": {"servers": [{"url": "h2c://analyzer:5000"}]}},
+				{"name": "api@docker", "loadBalancer": {"servers": [{"url": "http://api:8585"}]}},
+				{"name": "ui@docker", "weighted": {"services": [{"name": "ui-blue@docker"}]}}
+			]`,
+			want: []aggregator.DiscoveredNode{
+				node("analyzer", "Traefik service analyzer@docker", "http://analyzer:5000", probe.TypeGRPC),
+				node("api", "Traefik service api@docker", "http://api:8585", probe.TypeHTTP),
+			},
+			wantSkipped: []aggregator.SkippedNode{{Source: "Traefik service ui@docker", Reason: reasonNoServers}},
+		},
+		{
+			name:           "path prefix",
+			containerBased: true,
+			prefix:         true,
+			path:           traefikRawDataURL,
+			body: `{
+				"routers": {"api@docker": {"service": "api", "rule": "PathPrefix(` + "`/api`" + `)"}},
+				"services": {"api@docker": {"loadBalancer": {"servers": [{"url": "http://api:8585"}]}}}
+			}`,
+			want: []aggregator.DiscoveredNode{node("api", "Traefik service api@docker", "http://api:8585/api", probe.TypeHTTP)},
+		},
+		{
+			name: "local provider",
+			path: traefikLocalProvidersURL,
+			body: `{"file": {"backends": {
+				"api": {"servers": {"server-1": {"url": "http://api:8585"}}},
+				"empty": {}
+			}}}`,
+			want:        []aggregator.DiscoveredNode{node("api", "Traefik backend api", "http://api:8585", probe.TypeHTTP)},
+			wantSkipped: []aggregator.SkippedNode{{Source: "Traefik backend empty", Reason: reasonNoServers}},
+		},
+	}
+	probes, err := probe.NewClient(&probe.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					w.WriteHeader(http.StatusNotFound)
+
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			d := NewAggregator(srv.URL, tt.v2, tt.containerBased, tt.prefix, probes).Discover(context.Background())
+			if d.Error != "" {
+				t.Fatalf("Discover() error = %v", d.Error)
+			}
+			if !reflect.DeepEqual(d.Nodes, tt.want) {
+				t.Errorf("Discover() nodes = %+v, want %+v", d.Nodes, tt.want)
+			}
+			if !reflect.DeepEqual(d.Skipped, tt.wantSkipped) {
+				t.Errorf("Discover() skipped = %+v, want %+v", d.Skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestAggregator_applyScheme(t *testing.T) {
+	probes, err := probe.NewClient(&probe.Config{Services: map[string]*probe.ServiceConfig{
+		"api":  {Scheme: probe.SchemeHTTPS},
+		"jobs": {Type: probe.TypeGRPC},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Aggregator{probes: probes}
+
+	tests := []struct {
+		name, node, url string
+		wantURL         string
+		wantType        string
+	}{
+		{name: "plain http", node: "ui", url: "http://ui:8080", wantURL: "http://ui:8080", wantType: probe.TypeHTTP},
+		{name: "h2c server", node: "analyzer", url: "h2c://analyzer:5000", wantURL: "http://analyzer:5000", wantType: probe.TypeGRPC},
+		{name: "configured scheme", node: "api", url: "http://api:8585", wantURL: "https://api:8585", wantType: probe.TypeHTTP},
+		{name: "configured type", node: "jobs", url: "http://jobs:8686", wantURL: "http://jobs:8686", wantType: probe.TypeGRPC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ni := &NodeInfo{URL: tt.url}
+			a.applyScheme(tt.node, ni)
+			if ni.URL != tt.wantURL || ni.probeType != tt.wantType {
+				t.Errorf("applyScheme() = %s %s, want %s %s", ni.probeType, ni.URL, tt.wantType, tt.wantURL)
+			}
+		})
+	}
+}